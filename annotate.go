@@ -0,0 +1,195 @@
+package check
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------
+// Annotations of failures at the location of the failed checks, for
+// continuous integration services to show them inline on the code.
+
+// An annotation of a failed check, error or panic, or of a call which
+// failed without one.
+type annotation struct {
+	title    string // As in "FAIL: MySuite.TestFoo".
+	file     string
+	line     int
+	message  string
+	panicked bool
+}
+
+// Describe the problems of the call, if it has any worth annotating.
+// Tests reporting the panic of their fixture aren't annotated, since
+// the fixture call is.
+func annotations(result *CallResult) []annotation {
+	var title, message string
+	switch result.Status {
+	case CallFailed:
+		title, message = "FAIL", "Failed"
+		if result.ExpectedFailure {
+			message = "Succeeded, but was expected to fail: " + result.Reason
+		}
+	case CallPanicked:
+		title, message = "PANIC", "Panicked"
+	case CallTimedOut:
+		title, message = "TIMEOUT", "Timed out"
+	default:
+		return nil
+	}
+	title += ": " + result.Name
+	if result.Iteration > 0 {
+		title += fmt.Sprintf(" (iteration %d)", result.Iteration)
+	}
+	var annotations []annotation
+	for _, failure := range result.Failures {
+		annotations = append(annotations, annotation{
+			title:    title,
+			file:     failure.File,
+			line:     failure.Line,
+			message:  failure.Message,
+			panicked: strings.HasPrefix(failure.Message, "Panic: "),
+		})
+	}
+	if len(annotations) == 0 {
+		annotations = append(annotations, annotation{
+			title:    title,
+			file:     result.File,
+			line:     result.Line,
+			message:  message,
+			panicked: result.Status == CallPanicked,
+		})
+	}
+	return annotations
+}
+
+// Return the path relative to the root directory named by the given
+// environment variable, if it's set and the path is within it.
+func rootRelativePath(path, rootEnv string) string {
+	if root := os.Getenv(rootEnv); root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// -----------------------------------------------------------------------
+// GitHub Actions workflow commands.
+
+type githubAnnotator struct {
+	writer io.Writer
+}
+
+func newGitHubAnnotator(writer io.Writer) *githubAnnotator {
+	return &githubAnnotator{writer: writer}
+}
+
+func (a *githubAnnotator) RunStarted()                                {}
+func (a *githubAnnotator) SuiteStarted(suite string)                  {}
+func (a *githubAnnotator) CallStarted(call *CallInfo)                 {}
+func (a *githubAnnotator) SuiteFinished(suite string, result *Result) {}
+func (a *githubAnnotator) RunFinished(result *Result)                 {}
+
+func (a *githubAnnotator) CallFinished(result *CallResult) {
+	for _, an := range annotations(result) {
+		fmt.Fprintf(a.writer, "::error file=%s,line=%d,title=%s::%s\n",
+			githubEscapeProperty(rootRelativePath(an.file, "GITHUB_WORKSPACE")),
+			an.line, githubEscapeProperty(an.title), githubEscapeData(an.message))
+	}
+}
+
+var githubDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+var githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+func githubEscapeData(s string) string {
+	return githubDataEscaper.Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return githubPropertyEscaper.Replace(s)
+}
+
+// -----------------------------------------------------------------------
+// GitLab Code Quality report.
+
+type codeQualityReport struct {
+	m      sync.Mutex
+	path   string
+	issues []*codeQualityIssue
+}
+
+// Field names as documented for the Code Quality report artifact.
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+func newCodeQualityReport(path string) *codeQualityReport {
+	return &codeQualityReport{path: path}
+}
+
+func (r *codeQualityReport) RunStarted()                                {}
+func (r *codeQualityReport) SuiteStarted(suite string)                  {}
+func (r *codeQualityReport) CallStarted(call *CallInfo)                 {}
+func (r *codeQualityReport) SuiteFinished(suite string, result *Result) {}
+
+func (r *codeQualityReport) CallFinished(result *CallResult) {
+	for _, an := range annotations(result) {
+		issue := &codeQualityIssue{
+			Description: an.title,
+			CheckName:   "gocheck",
+			Severity:    "major",
+		}
+		if an.message != "" {
+			issue.Description += "\n" + an.message
+		}
+		if an.panicked {
+			issue.Severity = "critical"
+		}
+		issue.Location.Path = rootRelativePath(an.file, "CI_PROJECT_DIR")
+		issue.Location.Lines.Begin = an.line
+		sum := md5.Sum([]byte(fmt.Sprintf("%s\n%s:%d\n%s", an.title, issue.Location.Path, an.line, an.message)))
+		issue.Fingerprint = hex.EncodeToString(sum[:])
+
+		r.m.Lock()
+		r.issues = append(r.issues, issue)
+		r.m.Unlock()
+	}
+}
+
+// Save the issues as a JSON array, an empty one if nothing failed, and
+// turn a file that can't be written into a run error.
+func (r *codeQualityReport) RunFinished(result *Result) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	issues := r.issues
+	if issues == nil {
+		issues = []*codeQualityIssue{}
+	}
+	data, err := json.MarshalIndent(issues, "", "\t")
+	if err == nil {
+		err = ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+	}
+	if err != nil && result.RunError == nil {
+		result.RunError = fmt.Errorf("Cannot write Code Quality report: %v", err)
+	}
+}
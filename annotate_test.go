@@ -0,0 +1,96 @@
+package check_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&annotateS{})
+
+type annotateS struct{}
+
+type AnnotateHelper struct {
+	checkLine, errorLine int
+}
+
+func (s *AnnotateHelper) TestChecks(c *C) {
+	s.checkLine = getMyLine() + 1
+	c.Check(1, Equals, 2)
+	s.errorLine = getMyLine() + 1
+	c.Errorf("Oops, %d%% done", 50)
+}
+
+func (s *AnnotateHelper) TestPlainFail(c *C) {
+	c.Fail()
+}
+
+func (s *annotateS) TestFailures(c *C) {
+	helper := AnnotateHelper{}
+	result, _ := runCapturing(&RunConf{Filter: "TestChecks"}, &helper)
+	c.Assert(result.Tests, HasLen, 1)
+	failures := result.Tests[0].Failures
+	c.Assert(failures, HasLen, 2)
+	c.Check(filepath.Base(failures[0].File), Equals, "annotate_test.go")
+	c.Check(failures[0].Line, Equals, helper.checkLine)
+	c.Check(failures[0].Message, Equals, "... obtained int = 1\n... expected int = 2")
+	c.Check(failures[1].Line, Equals, helper.errorLine)
+	c.Check(failures[1].Message, Equals, "... Error: Oops, 50% done")
+}
+
+func (s *annotateS) TestGitHub(c *C) {
+	helper, outcomes := AnnotateHelper{}, OutcomeHelper{}
+	var buf bytes.Buffer
+	runCapturing(&RunConf{GitHubWriter: &buf}, &helper, &outcomes)
+	c.Check(buf.String(), Matches, ""+
+		"::error file=.*annotate_test\\.go,line="+strconv.Itoa(helper.checkLine)+",title=FAIL%3A AnnotateHelper\\.TestChecks::"+
+		"\\.\\.\\. obtained int = 1%0A\\.\\.\\. expected int = 2\n"+
+		"::error file=.*annotate_test\\.go,line="+strconv.Itoa(helper.errorLine)+",title=FAIL%3A AnnotateHelper\\.TestChecks::"+
+		"\\.\\.\\. Error: Oops, 50%25 done\n"+
+		"::error file=.*annotate_test\\.go,line=[0-9]+,title=FAIL%3A AnnotateHelper\\.TestPlainFail::Failed\n"+
+		"::error file=.*check_test\\.go,line="+strconv.Itoa(outcomes.failLine)+",title=FAIL%3A OutcomeHelper\\.TestFail::"+
+		"\\.\\.\\. obtained int = 1%0A\\.\\.\\. expected int = 2\n"+
+		"::error file=.*check_test\\.go,line="+strconv.Itoa(outcomes.panicLine)+",title=PANIC%3A OutcomeHelper\\.TestPanic::"+
+		"Panic: Boom!\n")
+}
+
+func (s *annotateS) TestCodeQuality(c *C) {
+	path := filepath.Join(c.MkDir(), "codequality.json")
+	helper, outcomes := AnnotateHelper{}, OutcomeHelper{}
+	result, _ := runCapturing(&RunConf{CodeQualityFile: path, Filter: "TestChecks|TestPanic"}, &helper, &outcomes)
+	c.Assert(result.RunError, IsNil)
+
+	var issues []struct {
+		Description string `json:"description"`
+		CheckName   string `json:"check_name"`
+		Fingerprint string `json:"fingerprint"`
+		Severity    string `json:"severity"`
+		Location    struct {
+			Path  string `json:"path"`
+			Lines struct {
+				Begin int `json:"begin"`
+			} `json:"lines"`
+		} `json:"location"`
+	}
+	c.Assert(json.Unmarshal([]byte(readReport(c, path)), &issues), IsNil)
+	c.Assert(issues, HasLen, 3)
+	c.Check(issues[0].Description, Equals, "FAIL: AnnotateHelper.TestChecks\n... obtained int = 1\n... expected int = 2")
+	c.Check(issues[0].CheckName, Equals, "gocheck")
+	c.Check(issues[0].Severity, Equals, "major")
+	c.Check(filepath.Base(issues[0].Location.Path), Equals, "annotate_test.go")
+	c.Check(issues[0].Location.Lines.Begin, Equals, helper.checkLine)
+	c.Check(issues[0].Fingerprint, Not(Equals), issues[1].Fingerprint)
+	c.Check(issues[2].Description, Equals, "PANIC: OutcomeHelper.TestPanic\nPanic: Boom!")
+	c.Check(issues[2].Severity, Equals, "critical")
+	c.Check(filepath.Base(issues[2].Location.Path), Equals, "check_test.go")
+	c.Check(issues[2].Location.Lines.Begin, Equals, outcomes.panicLine)
+}
+
+func (s *annotateS) TestCodeQualityWithoutFailures(c *C) {
+	path := filepath.Join(c.MkDir(), "codequality.json")
+	runCapturing(&RunConf{CodeQualityFile: path, Filter: "TestPass"}, &OutcomeHelper{})
+	c.Check(readReport(c, path), Equals, "[]\n")
+}
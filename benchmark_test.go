@@ -74,6 +74,23 @@ func (s *BenchmarkS) TestBenchmarkBytes(c *C) {
 	c.Assert(output.value, Matches, expected)
 }
 
+func (s *BenchmarkS) TestBenchmarkResult(c *C) {
+	helper := FixtureHelper{sleep: 100000}
+	output := String{}
+	runConf := RunConf{
+		Output:        &output,
+		Benchmark:     true,
+		BenchmarkTime: 10000000,
+		Filter:        "Benchmark2",
+	}
+	result := Run(&helper, &runConf)
+	c.Assert(result.Tests, HasLen, 1)
+	c.Assert(result.Tests[0].Benchmark, NotNil)
+	c.Check(result.Tests[0].Benchmark.N > 0, Equals, true)
+	c.Check(result.Tests[0].Benchmark.Bytes, Equals, int64(1024))
+	c.Check(result.Tests[0].Benchmark.NsPerOp() >= 100000, Equals, true)
+}
+
 func (s *BenchmarkS) TestBenchmarkMem(c *C) {
 	helper := FixtureHelper{sleep: 100000}
 	output := String{}
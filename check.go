@@ -8,10 +8,13 @@ package check
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path"
 	"path/filepath"
@@ -22,6 +25,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"testing"
 	"time"
 )
 
@@ -42,6 +46,8 @@ const (
 	panickedSt
 	fixturePanickedSt
 	missedSt
+	timedOutSt
+	retriedSt // Failed, and another attempt was started.
 )
 
 type funcStatus uint32
@@ -91,6 +97,33 @@ type C struct {
 	tempDir   *tempDir
 	benchMem  bool
 	startTime time.Time
+	wallStart time.Time // By the runner's clock.
+	wall      time.Duration
+	runner    *suiteRunner
+	scope     *callScope
+	parent    *C // Set on subtests only.
+	depth     int
+	iteration int  // Of a test run repeatedly, counting from 1.
+	muted     bool // An earlier iteration failed, so don't report problems.
+	retries   int  // Maximum number of attempts after the first one.
+	retried   int  // Number of earlier failed attempts.
+	flaky     string
+	slow      bool // Took longer than RunConf.SlowTest.
+	failures  []Failure
+	failOpen  bool // The last failure awaits its message, logged from failStart.
+	failStart int
+	finished  uint32
+	wake      chan bool
+
+	// Guards the fields below, which are also accessed by the
+	// goroutine watching for the test timeout.
+	timeoutm    sync.Mutex
+	timeout     time.Duration
+	timeoutBase time.Time
+	parallel    bool
+	paused      bool
+	nested      *C
+
 	timer
 }
 
@@ -99,7 +132,116 @@ func (c *C) status() funcStatus {
 }
 
 func (c *C) setStatus(s funcStatus) {
-	atomic.StoreUint32((*uint32)(&c._status), uint32(s))
+	for {
+		old := atomic.LoadUint32((*uint32)(&c._status))
+		if funcStatus(old) == timedOutSt {
+			// Final, as the call was already reported.
+			return
+		}
+		if atomic.CompareAndSwapUint32((*uint32)(&c._status), old, uint32(s)) {
+			return
+		}
+	}
+}
+
+// Time taken by the call. A timed out call may still be running,
+// so its timer is off limits.
+func (c *C) elapsed() time.Duration {
+	if c.status() == timedOutSt {
+		return time.Since(c.startTime)
+	}
+	return c.duration
+}
+
+// Describe the call to reporters.
+func (c *C) callInfo() *CallInfo {
+	pc := c.method.PC()
+	info := &CallInfo{
+		Suite:     c.method.suiteName(),
+		Method:    c.method.Info.Name,
+		Name:      niceFuncName(pc),
+		Test:      c.testName,
+		Fixture:   c.kind != testKd,
+		Iteration: c.iteration,
+		File:      "<unknown path>",
+	}
+	if c.parent != nil {
+		info.Name = c.testName
+	}
+	if c.kind == testKd && info.Test == "" {
+		info.Test = c.method.String()
+	}
+	if function := runtime.FuncForPC(pc); function != nil {
+		info.File, info.Line = function.FileLine(pc)
+	}
+	return info
+}
+
+// Describe the finished call to reporters.
+func (c *C) callResult() *CallResult {
+	status := c.status()
+	result := &CallResult{
+		CallInfo: *c.callInfo(),
+		Status:   CallStatus(status),
+		Duration: c.elapsed(),
+		Wall:     c.wall,
+		Log:      c.logb.String(),
+		Retries:  c.retried,
+		Slow:     c.slow,
+		logb:     c.logb,
+	}
+	if status != timedOutSt {
+		// The call may still be running otherwise.
+		result.Reason = c.reason
+		result.ExpectedFailure = c.mustFail
+		result.Failures = c.failures
+		if len(c.failures) > 0 {
+			result.FailureFile, result.FailureLine = c.failures[0].File, c.failures[0].Line
+		}
+	}
+	if status == succeededSt {
+		result.Timing = c.timerString()
+	}
+	return result
+}
+
+// finish marks the call as finished, and returns whether it wasn't
+// already, so that the call is only reported once when it times out.
+func (c *C) finish() bool {
+	if !atomic.CompareAndSwapUint32(&c.finished, 0, 1) {
+		return false
+	}
+	c.wakeWatcher()
+	return true
+}
+
+func (c *C) wakeWatcher() {
+	select {
+	case c.wake <- true:
+	default:
+	}
+}
+
+// deadline returns when the call times out, if it does.
+func (c *C) deadline() (deadline time.Time, ok bool) {
+	c.timeoutm.Lock()
+	defer c.timeoutm.Unlock()
+	if c.timeout <= 0 || c.paused {
+		return time.Time{}, false
+	}
+	return c.timeoutBase.Add(c.timeout), true
+}
+
+func (c *C) nestedCall() *C {
+	c.timeoutm.Lock()
+	defer c.timeoutm.Unlock()
+	return c.nested
+}
+
+func (c *C) setNestedCall(nested *C) {
+	c.timeoutm.Lock()
+	c.nested = nested
+	c.timeoutm.Unlock()
 }
 
 func (c *C) stopNow() {
@@ -124,12 +266,89 @@ func (l *logger) WriteTo(w io.Writer) (int64, error) {
 	return l.writer.WriteTo(w)
 }
 
+func (l *logger) Len() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.writer.Len()
+}
+
 func (l *logger) String() string {
 	l.Lock()
 	defer l.Unlock()
 	return l.writer.String()
 }
 
+// -----------------------------------------------------------------------
+// State shared by a test and its fixtures, or by the suite fixtures.
+
+type callScope struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	m        sync.Mutex
+	cleanups []cleanup
+}
+
+type cleanup struct {
+	f      func()
+	method *methodType // Method which registered the cleanup.
+}
+
+func newCallScope(parent context.Context) *callScope {
+	ctx, cancel := context.WithCancel(parent)
+	return &callScope{ctx: ctx, cancel: cancel}
+}
+
+func (scope *callScope) addCleanup(f func(), method *methodType) {
+	scope.m.Lock()
+	scope.cleanups = append(scope.cleanups, cleanup{f, method})
+	scope.m.Unlock()
+}
+
+func (scope *callScope) lastCleanupMethod() *methodType {
+	scope.m.Lock()
+	defer scope.m.Unlock()
+	if n := len(scope.cleanups); n > 0 {
+		return scope.cleanups[n-1].method
+	}
+	return nil
+}
+
+// Pop the last registered cleanup, if it was registered by the given
+// method, or by any method if it's nil.
+func (scope *callScope) popCleanup(method *methodType) *cleanup {
+	scope.m.Lock()
+	defer scope.m.Unlock()
+	n := len(scope.cleanups)
+	if n == 0 || method != nil && scope.cleanups[n-1].method != method {
+		return nil
+	}
+	cleanup := scope.cleanups[n-1]
+	scope.cleanups = scope.cleanups[:n-1]
+	return &cleanup
+}
+
+// Run the cleanups registered by the given method (or by any method if
+// it's nil), last ones first, reporting any panics against the call c.
+func (scope *callScope) runCleanups(c *C, method *methodType) {
+	for {
+		cleanup := scope.popCleanup(method)
+		if cleanup == nil {
+			return
+		}
+		c.runCleanup(cleanup.f)
+	}
+}
+
+func (c *C) runCleanup(f func()) {
+	defer func() {
+		if value := recover(); value != nil {
+			c.logPanic(1, value)
+			c.setStatus(panickedSt)
+		}
+	}()
+	f()
+}
+
 // -----------------------------------------------------------------------
 // Handling of temporary files and directories.
 
@@ -305,6 +524,23 @@ func (c *C) logCaller(skip int) {
 		c.logCode(testFile, testLine)
 	}
 	c.logCode(callerFile, callerLine)
+	if testFile == "" {
+		testFile, testLine = callerFile, callerLine
+	}
+	c.failures = append(c.failures, Failure{File: testFile, Line: testLine})
+	c.failOpen, c.failStart = true, c.logb.Len()
+}
+
+// Take the message of the last failure from what was logged since
+// logCaller, once the call is marked as failed.
+func (c *C) endFailure() {
+	if !c.failOpen {
+		return
+	}
+	if log := c.logb.String(); c.failStart <= len(log) {
+		c.failures[len(c.failures)-1].Message = strings.TrimSpace(log[c.failStart:])
+	}
+	c.failOpen = false
 }
 
 func (c *C) logCode(path string, line int) {
@@ -325,11 +561,16 @@ var asmGo = filepath.Join("runtime", "asm_")
 func (c *C) logPanic(skip int, value interface{}) {
 	skip++ // Our own frame.
 	initialSkip := skip
+	failure := Failure{Message: fmt.Sprintf("Panic: %s", value)}
+	testFunc := runtime.FuncForPC(c.method.PC())
 	for ; ; skip++ {
 		if pc, file, line, ok := runtime.Caller(skip); ok {
 			if skip == initialSkip {
 				c.logf("... Panic: %s (PC=0x%X)\n", value, pc)
 			}
+			if failure.File == "" && runtime.FuncForPC(pc) == testFunc {
+				failure.File, failure.Line = file, line
+			}
 			name := niceFuncName(pc)
 			path := nicePath(file)
 			if strings.Contains(path, "/gopkg.in/check.v") {
@@ -346,6 +587,10 @@ func (c *C) logPanic(skip int, value interface{}) {
 			break
 		}
 	}
+	if failure.File == "" && testFunc != nil {
+		failure.File, failure.Line = testFunc.FileLine(c.method.PC())
+	}
+	c.failures = append(c.failures, failure)
 }
 
 func (c *C) logSoftPanic(issue string) {
@@ -419,9 +664,36 @@ type Result struct {
 	Panicked         int
 	FixturePanicked  int
 	ExpectedFailures int
+	Flaky            int // Succeeded after failing, see C.Flaky.
+	TimedOut         int
 	Missed           int    // Not even tried to run, related to a panic in the fixture.
 	RunError         error  // Houston, we've got a problem.
 	WorkDir          string // If KeepWorkDir is true
+	Shuffle          string // Seed used to shuffle tests, if enabled
+	Stopped          bool   // If FailFast stopped the run
+
+	// Outcomes of each test run repeatedly, indexed by test name.
+	Repeats map[string]RepeatCounts
+
+	// Outcomes of each test, in the order they finished.
+	Tests []TestResult
+}
+
+// TestResult is the outcome of a test, including its subtests and
+// each iteration of tests run repeatedly.
+type TestResult struct {
+	CallResult
+
+	// Metrics of a benchmark which succeeded, or nil.
+	Benchmark *testing.BenchmarkResult
+}
+
+// RepeatCounts holds the outcomes of the iterations of a test run
+// repeatedly with RunConf.Count or RunConf.UntilFail.
+type RepeatCounts struct {
+	Succeeded    int
+	Failed       int // Including panics and timeouts.
+	FirstFailure int // Iteration of the first failure, counting from 1.
 }
 
 type resultTracker struct {
@@ -472,6 +744,8 @@ func (tracker *resultTracker) _loopRoutine() {
 					if c.kind == testKd {
 						if c.mustFail {
 							tracker.result.ExpectedFailures++
+						} else if c.retried > 0 {
+							tracker.result.Flaky++
 						} else {
 							tracker.result.Succeeded++
 						}
@@ -490,6 +764,10 @@ func (tracker *resultTracker) _loopRoutine() {
 					tracker.result.Missed++
 				case missedSt:
 					tracker.result.Missed++
+				case timedOutSt:
+					if c.kind == testKd {
+						tracker.result.TimedOut++
+					}
 				case skippedSt:
 					if c.kind == testKd {
 						tracker.result.Skipped++
@@ -525,6 +803,27 @@ type suiteRunner struct {
 	reportedProblemLast       bool
 	benchTime                 time.Duration
 	benchMem                  bool
+	parallelPaused            chan *C   // Receives tests which called Parallel.
+	parallelResume            chan bool // Closed when paused tests may resume.
+	parallelSem               chan bool // Limits the number of parallel tests.
+	parallelTests             []*C
+	testTimeout               time.Duration
+	abortOnTimeout            bool
+	_aborted                  int32
+	suiteScope                *callScope
+	subFilters                []*regexp.Regexp // Per level of subtests.
+	count                     int
+	untilFail                 bool
+	repeats                   map[string]RepeatCounts
+	retries                   int
+	failFast                  bool
+	slowTest                  time.Duration
+	slowTestFails             bool
+	state                     *runState
+	testingT                  bool // Tests are reported as testing.T subtests.
+	testResultsm              sync.Mutex
+	testResults               []TestResult
+	now                       func() time.Time
 }
 
 type RunConf struct {
@@ -536,6 +835,80 @@ type RunConf struct {
 	BenchmarkTime time.Duration // Defaults to 1 second
 	BenchmarkMem  bool
 	KeepWorkDir   bool
+	Parallelism   int // Defaults to GOMAXPROCS
+
+	// Maximum time each test may take, see C.SetTimeout. After a test
+	// times out the remaining tests of its suite are run, unless
+	// AbortOnTimeout is set, in which case they're reported as missed.
+	TestTimeout    time.Duration
+	AbortOnTimeout bool
+
+	// Shuffle the order of tests within suites, and of suites in RunAll.
+	// It may be "off" (the default), "on", or the seed to shuffle with.
+	// The seed used is reported in Result.Shuffle.
+	Shuffle string
+
+	// Run each test Count times, or until it fails if UntilFail is set,
+	// within a single run of SetUpSuite and TearDownSuite. Only the first
+	// failing iteration of each test is reported, and the outcomes of the
+	// iterations are counted in Result.Repeats. With UntilFail set and
+	// Count unset, tests are repeated for as long as they succeed.
+	Count     int // Defaults to 1
+	UntilFail bool
+
+	// Number of times failed tests are retried, see C.Flaky.
+	Retries int
+
+	// Run only one shard of the tests, as "i/n" with i counting from 0,
+	// splitting tests across n shards by a hash of their names. Suites
+	// with no tests in the shard don't run their fixtures at all.
+	Shard string
+
+	// Write a JUnit XML report of the run to the given file.
+	JUnitFile string
+
+	// Write the events of the run to the given writer as JSON objects,
+	// one per line, in the same format as go tool test2json.
+	EventWriter io.Writer
+
+	// Annotate each failed check, error and panic at its location, with
+	// GitHub Actions workflow commands written to GitHubWriter, or in a
+	// GitLab Code Quality report written to CodeQualityFile.
+	GitHubWriter    io.Writer
+	CodeQualityFile string
+
+	// Write the results of the run to the given writer in the Test
+	// Anything Protocol, with one line per test.
+	TAPWriter io.Writer
+
+	// Notify the given reporters of the progress of the run, in addition
+	// to writing it to Output.
+	Reporters []Reporter
+
+	// Stop the run at the first test which fails or panics. Running
+	// tests are waited for and pending teardown fixtures are still run,
+	// but no further tests or suites are started, and the tests which
+	// weren't run are counted as missed.
+	FailFast bool
+
+	// Report the given number of slowest tests at the end of the run,
+	// along with the time spent in SetUpSuite and TearDownSuite.
+	Slowest int
+
+	// Report tests which succeed but take longer than SlowTest, or fail
+	// them if SlowTestFails is set.
+	SlowTest      time.Duration
+	SlowTestFails bool
+
+	// Color the output: "auto" (the default) colors it only when Output
+	// is a terminal, and it may also be "always" or "never".
+	Color string
+
+	// Number of suites run concurrently by RunAll. The output of each
+	// suite is written out at once after it finishes. Defaults to 1.
+	SuiteParallelism int
+
+	now func() time.Time // Clock timing the calls, replaced in tests.
 }
 
 // Create a new suiteRunner able to run all methods in the given suite.
@@ -556,28 +929,71 @@ func newSuiteRunner(suite interface{}, runConf *RunConf) *suiteRunner {
 	suiteValue := reflect.ValueOf(suite)
 
 	runner := &suiteRunner{
-		suite:     suite,
-		output:    newOutputWriter(conf.Output, conf.Stream, conf.Verbose),
-		tracker:   newResultTracker(),
-		benchTime: conf.BenchmarkTime,
-		benchMem:  conf.BenchmarkMem,
-		tempDir:   &tempDir{},
-		keepDir:   conf.KeepWorkDir,
-		tests:     make([]*methodType, 0, suiteNumMethods),
+		suite:          suite,
+		output:         newOutputWriter(conf.Output, conf.Stream, conf.Verbose),
+		tracker:        newResultTracker(),
+		benchTime:      conf.BenchmarkTime,
+		benchMem:       conf.BenchmarkMem,
+		tempDir:        &tempDir{},
+		keepDir:        conf.KeepWorkDir,
+		tests:          make([]*methodType, 0, suiteNumMethods),
+		parallelPaused: make(chan *C),
+		parallelResume: make(chan bool),
+		testTimeout:    conf.TestTimeout,
+		abortOnTimeout: conf.AbortOnTimeout,
+		count:          conf.Count,
+		untilFail:      conf.UntilFail,
+		retries:        conf.Retries,
+		failFast:       conf.FailFast,
+		slowTest:       conf.SlowTest,
+		slowTestFails:  conf.SlowTestFails,
+		state:          &runState{},
+		now:            conf.now,
+	}
+	if runner.now == nil {
+		runner.now = time.Now
 	}
 	if runner.benchTime == 0 {
 		runner.benchTime = 1 * time.Second
 	}
+	if conf.Parallelism <= 0 {
+		conf.Parallelism = runtime.GOMAXPROCS(0)
+	}
+	runner.parallelSem = make(chan bool, conf.Parallelism)
+	if runner.count <= 0 {
+		runner.count = 1
+	}
 
 	var filterRegexp *regexp.Regexp
 	if conf.Filter != "" {
-		regexp, err := regexp.Compile(conf.Filter)
-		if err != nil {
-			msg := "Bad filter expression: " + err.Error()
-			runner.tracker.result.RunError = errors.New(msg)
-			return runner
+		for i, expr := range splitFilter(conf.Filter) {
+			var re *regexp.Regexp
+			if expr != "" {
+				var err error
+				re, err = regexp.Compile(expr)
+				if err != nil {
+					msg := "Bad filter expression: " + err.Error()
+					runner.tracker.result.RunError = errors.New(msg)
+					return runner
+				}
+			}
+			if i == 0 {
+				filterRegexp = re
+			} else {
+				runner.subFilters = append(runner.subFilters, re)
+			}
 		}
-		filterRegexp = regexp
+	}
+
+	shard, shards, err := parseShard(conf.Shard)
+	if err != nil {
+		runner.tracker.result.RunError = err
+		return runner
+	}
+	runner.output.Color, err = parseColor(conf.Color, conf.Output)
+	if err != nil {
+		runner.tracker.result.RunError = err
+		return runner
 	}
 
 	for i := 0; i != suiteNumMethods; i++ {
@@ -599,50 +1015,175 @@ func newSuiteRunner(suite interface{}, runConf *RunConf) *suiteRunner {
 			if !strings.HasPrefix(method.Info.Name, prefix) {
 				continue
 			}
-			if filterRegexp == nil || method.matches(filterRegexp) {
-				runner.tests = append(runner.tests, method)
+			if filterRegexp != nil && !method.matches(filterRegexp) {
+				continue
+			}
+			if shards > 1 && shardOf(method.String(), shards) != shard {
+				continue
 			}
+			runner.tests = append(runner.tests, method)
+		}
+	}
+
+	seed, shuffle, err := parseShuffle(conf.Shuffle)
+	if err != nil {
+		runner.tracker.result.RunError = err
+		return runner
+	}
+	if shuffle {
+		tests := runner.tests
+		runner.tests = make([]*methodType, len(tests))
+		for i, j := range rand.New(rand.NewSource(seed)).Perm(len(tests)) {
+			runner.tests[i] = tests[j]
 		}
+		runner.tracker.result.Shuffle = strconv.FormatInt(seed, 10)
 	}
 	return runner
 }
 
+// Parse the Shard setting, returning the shard to run out of how many.
+func parseShard(value string) (shard, shards int, err error) {
+	if value == "" {
+		return 0, 1, nil
+	}
+	bad := errors.New("Bad shard value: " + value)
+	slash := strings.Index(value, "/")
+	if slash < 0 {
+		return 0, 0, bad
+	}
+	shard, err = strconv.Atoi(value[:slash])
+	if err != nil {
+		return 0, 0, bad
+	}
+	shards, err = strconv.Atoi(value[slash+1:])
+	if err != nil || shard < 0 || shard >= shards {
+		return 0, 0, bad
+	}
+	return shard, shards, nil
+}
+
+// Return the shard the named test belongs to, out of the given number of
+// shards. The hash is stable, so tests stay in their shards across runs.
+func shardOf(name string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// Parse the Shuffle setting, returning the seed to shuffle with, if any.
+func parseShuffle(value string) (seed int64, shuffle bool, err error) {
+	switch value {
+	case "", "off":
+		return 0, false, nil
+	case "on":
+		return time.Now().UnixNano(), true, nil
+	}
+	seed, err = strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, errors.New("Bad shuffle value: " + value)
+	}
+	return seed, true, nil
+}
+
+// Split the filter expression on the slashes which aren't within brackets
+// or parentheses. The first element selects tests, and the following ones
+// select subtests at the respective level.
+func splitFilter(filter string) []string {
+	var exprs []string
+	brackets, parens, start := 0, 0, 0
+	for i := 0; i < len(filter); i++ {
+		switch filter[i] {
+		case '[':
+			brackets++
+		case ']':
+			if brackets > 0 {
+				brackets--
+			}
+		case '(':
+			if brackets == 0 {
+				parens++
+			}
+		case ')':
+			if brackets == 0 {
+				parens--
+			}
+		case '\\':
+			i++
+		case '/':
+			if brackets == 0 && parens == 0 {
+				exprs = append(exprs, filter[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(exprs, filter[start:])
+}
+
 // Run all methods in the given suite.
 func (runner *suiteRunner) run() *Result {
 	if runner.tracker.result.RunError == nil && len(runner.tests) > 0 {
 		runner.tracker.start()
-		if runner.checkFixtureArgs() {
+		runner.state.suiteStarted(runner.tests[0].suiteName())
+		runner.suiteScope = newCallScope(context.Background())
+		if runner.aborted() {
+			runner.skipTests(missedSt, runner.tests)
+			runner.suiteScope.cancel()
+		} else if runner.checkFixtureArgs() {
 			c := runner.runFixture(runner.setUpSuite, "", nil)
 			if c == nil || c.status() == succeededSt {
 				for i := 0; i != len(runner.tests); i++ {
-					c := runner.runTest(runner.tests[i])
-					if c.status() == fixturePanickedSt {
+					if runner.aborted() {
+						runner.skipTests(missedSt, runner.tests[i:])
+						break
+					}
+					c := runner.repeatTest(runner.tests[i])
+					if !c.parallel && c.status() == fixturePanickedSt {
 						runner.skipTests(missedSt, runner.tests[i+1:])
 						break
 					}
 				}
+				runner.waitParallel()
 			} else if c != nil && c.status() == skippedSt {
 				runner.skipTests(skippedSt, runner.tests)
 			} else {
 				runner.skipTests(missedSt, runner.tests)
 			}
+			runner.suiteScope.cancel()
 			runner.runFixture(runner.tearDownSuite, "", nil)
+			runner.runSuiteCleanups()
 		} else {
 			runner.skipTests(missedSt, runner.tests)
+			runner.suiteScope.cancel()
 		}
-		runner.tracker.waitAndStop()
-		if runner.keepDir {
-			runner.tracker.result.WorkDir = runner.tempDir.path
-		} else {
-			runner.tempDir.removeAll()
-		}
+		runner.wrapUp()
 	}
 	return &runner.tracker.result
 }
 
+// Wait for the results of all the calls, and clean up after the suite.
+func (runner *suiteRunner) wrapUp() {
+	runner.tracker.waitAndStop()
+	runner.tracker.result.Repeats = runner.repeats
+	runner.tracker.result.Stopped = runner.state.stopped()
+	runner.tracker.result.Tests = runner.testResults
+	if runner.keepDir {
+		runner.tracker.result.WorkDir = runner.tempDir.path
+	} else {
+		runner.tempDir.removeAll()
+	}
+	runner.state.suiteFinished(runner.tests[0].suiteName(), &runner.tracker.result)
+}
+
 // Create a call object with the given suite method, and fork a
 // goroutine with the provided dispatcher for running it.
-func (runner *suiteRunner) forkCall(method *methodType, kind funcKind, testName string, logb *logger, dispatcher func(c *C)) *C {
+func (runner *suiteRunner) forkCall(method *methodType, kind funcKind, testName string, logb *logger, scope *callScope, dispatcher func(c *C)) *C {
+	c := runner.newCall(method, kind, testName, logb, scope)
+	runner.startCall(c, dispatcher)
+	return c
+}
+
+// Create a call object with the given suite method.
+func (runner *suiteRunner) newCall(method *methodType, kind funcKind, testName string, logb *logger, scope *callScope) *C {
 	var logw io.Writer
 	if runner.output.Stream {
 		logw = runner.output
@@ -660,21 +1201,43 @@ func (runner *suiteRunner) forkCall(method *methodType, kind funcKind, testName
 		done:      make(chan *C, 1),
 		timer:     timer{benchTime: runner.benchTime},
 		startTime: time.Now(),
+		wallStart: runner.now(),
 		benchMem:  runner.benchMem,
+		runner:    runner,
+		scope:     scope,
+		wake:      make(chan bool, 1),
+		timeout:   runner.testTimeout,
+		retries:   runner.retries,
 	}
+	c.timeoutBase = c.startTime
+	return c
+}
+
+// Fork a goroutine with the provided dispatcher for running the call.
+func (runner *suiteRunner) startCall(c *C, dispatcher func(c *C)) {
 	runner.tracker.expectCall(c)
 	go (func() {
 		runner.reportCallStarted(c)
 		defer runner.callDone(c)
 		dispatcher(c)
 	})()
-	return c
 }
 
 // Same as forkCall(), but wait for call to finish before returning.
 func (runner *suiteRunner) runFunc(method *methodType, kind funcKind, testName string, logb *logger, dispatcher func(c *C)) *C {
-	c := runner.forkCall(method, kind, testName, logb, dispatcher)
+	c := runner.forkCall(method, kind, testName, logb, runner.suiteScope, dispatcher)
+	<-c.done
+	return c
+}
+
+// Start the call and wait for it to finish, recording it as nested within
+// the parent call while it runs, so that it's finished together with the
+// parent in case the parent times out.
+func (runner *suiteRunner) runNested(parent *C, c *C, dispatcher func(c *C)) *C {
+	parent.setNestedCall(c)
+	runner.startCall(c, dispatcher)
 	<-c.done
+	parent.setNestedCall(nil)
 	return c
 }
 
@@ -682,6 +1245,11 @@ func (runner *suiteRunner) runFunc(method *methodType, kind funcKind, testName s
 // accordingly.  Then, mark the call as done and report to the tracker.
 func (runner *suiteRunner) callDone(c *C) {
 	value := recover()
+	if !c.finish() {
+		// The call has timed out and was already reported.
+		return
+	}
+	c.wall = runner.now().Sub(c.wallStart)
 	if value != nil {
 		switch v := value.(type) {
 		case *fixturePanic:
@@ -706,36 +1274,294 @@ func (runner *suiteRunner) callDone(c *C) {
 			c.logString("Reason: " + c.reason)
 		}
 	}
+	runner.checkSlow(c)
+	if runner.retry(c) {
+		return
+	}
+	if c.parallel {
+		<-runner.parallelSem
+	}
 
 	runner.reportCallDone(c)
 	c.done <- c
 }
 
+// Flag a test which succeeded but took longer than RunConf.SlowTest,
+// failing it if RunConf.SlowTestFails is set.
+func (runner *suiteRunner) checkSlow(c *C) {
+	if runner.slowTest <= 0 || c.kind != testKd || c.parent != nil || c.N > 0 {
+		return
+	}
+	if c.status() != succeededSt || c.wall <= runner.slowTest {
+		return
+	}
+	c.slow = true
+	c.logString(fmt.Sprintf("Slow test: took %.3fs, longer than the %s limit", c.wall.Seconds(), runner.slowTest))
+	c.logNewLine()
+	if runner.slowTestFails {
+		c.setStatus(failedSt)
+	}
+}
+
+// Start another attempt of a failed test, if it may still be retried.
+// The new attempt keeps the log of the failed ones, and takes over the
+// done channel, so that whoever waits for the test waits for it instead.
+func (runner *suiteRunner) retry(c *C) bool {
+	if c.kind != testKd || c.parent != nil || c.retried >= c.retries {
+		return false
+	}
+	if st := c.status(); st != failedSt && st != panickedSt {
+		return false
+	}
+	if !strings.HasPrefix(c.method.Info.Name, "Test") {
+		return false
+	}
+	retry := fmt.Sprintf("Retrying: attempt %d of %d", c.retried+2, c.retries+1)
+	if c.flaky != "" {
+		retry += " (" + c.flaky + ")"
+	}
+	c.logString(retry)
+	c.setStatus(retriedSt)
+	runner.tracker.callDone(c)
+
+	next := runner.newCall(c.method, testKd, c.testName, c.logb, nil)
+	next.done = c.done
+	next.iteration = c.iteration
+	next.muted = c.muted
+	next.retries = c.retries
+	next.retried = c.retried + 1
+	next.flaky = c.flaky
+	next.parallel = c.parallel // Still holding the semaphore.
+	runner.startTest(next)
+	return true
+}
+
+// Watch the test for its deadline, and report it as timed out if it's
+// still running by then.
+func (runner *suiteRunner) watchTimeout(c *C) {
+	for atomic.LoadUint32(&c.finished) == 0 {
+		var t *time.Timer
+		var expired <-chan time.Time
+		if deadline, ok := c.deadline(); ok {
+			t = time.NewTimer(time.Until(deadline))
+			expired = t.C
+		}
+		select {
+		case <-c.wake:
+		case <-expired:
+			runner.timeOut(c)
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// Report the test as timed out, together with the stacks of all running
+// goroutines, and let the runner move on. The goroutine running the test
+// can't be stopped, so it's left behind, and it won't report anything
+// else should it ever finish.
+func (runner *suiteRunner) timeOut(c *C) {
+	if !c.finish() {
+		return
+	}
+	c.wall = runner.now().Sub(c.wallStart)
+	c.setStatus(timedOutSt)
+	c.scope.cancel()
+	for nested := c.nestedCall(); nested != nil; nested = nested.nestedCall() {
+		if nested.finish() {
+			nested.wall = runner.now().Sub(nested.wallStart)
+			nested.setStatus(timedOutSt)
+			if nested.kind == testKd {
+				nested.logString("Timeout: " + c.testName + " timed out")
+				runner.reportCallDone(nested)
+			} else {
+				runner.tracker.callDone(nested)
+			}
+		}
+	}
+	c.timeoutm.Lock()
+	timeout, parallel := c.timeout, c.parallel
+	c.timeoutm.Unlock()
+	c.logf("... Timeout: test still running after %s\n", timeout)
+	c.writeLog(goroutineStacks())
+	if parallel {
+		<-runner.parallelSem
+	}
+	if runner.abortOnTimeout {
+		runner.abort()
+	}
+	runner.reportCallDone(c)
+	c.done <- c
+}
+
+func goroutineStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Stop the runner from starting any further tests.
+func (runner *suiteRunner) abort() {
+	atomic.StoreInt32(&runner._aborted, 1)
+}
+
+func (runner *suiteRunner) aborted() bool {
+	return atomic.LoadInt32(&runner._aborted) == 1 || runner.state.stopped()
+}
+
+// State shared by the runners of all the suites in a run.
+type runState struct {
+	_stopped  int32
+	m         sync.Mutex // Serializes the calls to reporters.
+	reporters []Reporter
+}
+
+// Implemented by reporters which follow tests being paused by Parallel.
+type pauseReporter interface {
+	callPaused(call *CallInfo, paused bool)
+}
+
+func newRunState(runConf *RunConf, suites []interface{}) *runState {
+	state := &runState{}
+	if runConf != nil {
+		if runConf.JUnitFile != "" {
+			state.reporters = append(state.reporters, newJUnitReport(runConf.JUnitFile, suites))
+		}
+		if runConf.EventWriter != nil {
+			state.reporters = append(state.reporters, newEventWriter(runConf.EventWriter, suites))
+		}
+		if runConf.GitHubWriter != nil {
+			state.reporters = append(state.reporters, newGitHubAnnotator(runConf.GitHubWriter))
+		}
+		if runConf.CodeQualityFile != "" {
+			state.reporters = append(state.reporters, newCodeQualityReport(runConf.CodeQualityFile))
+		}
+		if runConf.Slowest > 0 {
+			output := runConf.Output
+			if output == nil {
+				output = os.Stdout
+			}
+			state.reporters = append(state.reporters, newTimingReporter(output, runConf.Slowest))
+		}
+		if runConf.TAPWriter != nil {
+			state.reporters = append(state.reporters, newTAPReporter(runConf.TAPWriter, tapPlan(suites, runConf)))
+		}
+		state.reporters = append(state.reporters, runConf.Reporters...)
+	}
+	state.m.Lock()
+	for _, reporter := range state.reporters {
+		reporter.RunStarted()
+	}
+	state.m.Unlock()
+	return state
+}
+
+func (state *runState) suiteStarted(suite string) {
+	state.m.Lock()
+	defer state.m.Unlock()
+	for _, reporter := range state.reporters {
+		reporter.SuiteStarted(suite)
+	}
+}
+
+func (state *runState) suiteFinished(suite string, result *Result) {
+	state.m.Lock()
+	defer state.m.Unlock()
+	for _, reporter := range state.reporters {
+		reporter.SuiteFinished(suite, result)
+	}
+}
+
+func (state *runState) callStarted(call *CallInfo) {
+	state.m.Lock()
+	defer state.m.Unlock()
+	for _, reporter := range state.reporters {
+		reporter.CallStarted(call)
+	}
+}
+
+// Record the test being paused by Parallel, or resumed.
+func (state *runState) callPaused(c *C, paused bool) {
+	if len(state.reporters) == 0 {
+		return
+	}
+	call := c.callInfo()
+	state.m.Lock()
+	defer state.m.Unlock()
+	for _, reporter := range state.reporters {
+		if reporter, ok := reporter.(pauseReporter); ok {
+			reporter.callPaused(call, paused)
+		}
+	}
+}
+
+func (state *runState) callDone(result *CallResult) {
+	state.m.Lock()
+	defer state.m.Unlock()
+	for _, reporter := range state.reporters {
+		reporter.CallFinished(result)
+	}
+}
+
+// Let the reporters know that all the suites of the run are done.
+func (state *runState) finish(result *Result) {
+	state.m.Lock()
+	defer state.m.Unlock()
+	for _, reporter := range state.reporters {
+		reporter.RunFinished(result)
+	}
+}
+
+// Stop all the runners from starting any further tests or suites.
+func (state *runState) stop() {
+	atomic.StoreInt32(&state._stopped, 1)
+}
+
+func (state *runState) stopped() bool {
+	return atomic.LoadInt32(&state._stopped) == 1
+}
+
 // Runs a fixture call synchronously.  The fixture will still be run in a
 // goroutine like all suite methods, but this method will not return
 // while the fixture goroutine is not done, because the fixture must be
 // run in a desired order.
 func (runner *suiteRunner) runFixture(method *methodType, testName string, logb *logger) *C {
+	return runner.runNestedFixture(nil, method, testName, logb)
+}
+
+// Same as runFixture(), but run the fixture nested within the given test.
+func (runner *suiteRunner) runNestedFixture(test *C, method *methodType, testName string, logb *logger) *C {
 	if method != nil {
-		c := runner.runFunc(method, fixtureKd, testName, logb, func(c *C) {
+		dispatcher := func(c *C) {
 			c.ResetTimer()
 			c.StartTimer()
 			defer c.StopTimer()
 			c.method.Call([]reflect.Value{reflect.ValueOf(c)})
-		})
-		return c
+		}
+		if test != nil {
+			c := runner.newCall(method, fixtureKd, testName, logb, test.scope)
+			return runner.runNested(test, c, dispatcher)
+		}
+		return runner.runFunc(method, fixtureKd, testName, logb, dispatcher)
 	}
 	return nil
 }
 
-// Run the fixture method with runFixture(), but panic with a fixturePanic{}
-// in case the fixture method panics.  This makes it easier to track the
-// fixture panic together with other call panics within forkTest().
-func (runner *suiteRunner) runFixtureWithPanic(method *methodType, testName string, logb *logger, skipped *bool) *C {
+// Run the fixture method with runNestedFixture(), but panic with a
+// fixturePanic{} in case the fixture method panics.  This makes it easier
+// to track the fixture panic together with other call panics within
+// forkTest().
+func (runner *suiteRunner) runFixtureWithPanic(test *C, method *methodType, testName string, logb *logger, skipped *bool) *C {
 	if skipped != nil && *skipped {
 		return nil
 	}
-	c := runner.runFixture(method, testName, logb)
+	c := runner.runNestedFixture(test, method, testName, logb)
 	if c != nil && c.status() != succeededSt {
 		if skipped != nil {
 			*skipped = c.status() == skippedSt
@@ -751,16 +1577,37 @@ type fixturePanic struct {
 }
 
 // Run the suite test method, together with the test-specific fixture,
-// asynchronously.
-func (runner *suiteRunner) forkTest(method *methodType) *C {
-	testName := method.String()
-	return runner.forkCall(method, testKd, testName, nil, func(c *C) {
+// asynchronously. The iteration is zero unless the test is repeated.
+func (runner *suiteRunner) forkTest(method *methodType, iteration int, muted bool) *C {
+	c := runner.newCall(method, testKd, method.String(), nil, nil)
+	c.iteration = iteration
+	c.muted = muted
+	runner.startTest(c)
+	return c
+}
+
+// Start running the test call, together with the test-specific fixture.
+func (runner *suiteRunner) startTest(c *C) {
+	testName := c.testName
+	scope := newCallScope(runner.suiteScope.ctx)
+	c.scope = scope
+	runner.startCall(c, func(c *C) {
+		defer func() {
+			if c.status() != timedOutSt {
+				runner.runTestCleanups(c)
+			}
+		}()
 		var skipped bool
-		defer runner.runFixtureWithPanic(runner.tearDownTest, testName, nil, &skipped)
+		defer func() {
+			if c.status() != timedOutSt {
+				runner.runFixtureWithPanic(c, runner.tearDownTest, testName, nil, &skipped)
+			}
+		}()
+		defer scope.cancel()
 		defer c.StopTimer()
 		benchN := 1
 		for {
-			runner.runFixtureWithPanic(runner.setUpTest, testName, c.logb, &skipped)
+			runner.runFixtureWithPanic(c, runner.setUpTest, testName, c.logb, &skipped)
 			mt := c.method.Type()
 			if mt.NumIn() != 1 || mt.In(0) != reflect.TypeOf(c) {
 				// Rather than a plain panic, provide a more helpful message when
@@ -801,19 +1648,150 @@ func (runner *suiteRunner) forkTest(method *methodType) *C {
 			benchN = roundUp(benchN)
 
 			skipped = true // Don't run the deferred one if this panics.
-			runner.runFixtureWithPanic(runner.tearDownTest, testName, nil, nil)
+			runner.runFixtureWithPanic(c, runner.tearDownTest, testName, nil, nil)
 			skipped = false
 		}
 	})
+	if strings.HasPrefix(c.method.Info.Name, "Test") {
+		go runner.watchTimeout(c)
+	}
+}
+
+// Run f as a subtest of the parent call, unless the filter deselects it,
+// in which case nil is returned.
+func (runner *suiteRunner) runSubtest(parent *C, name string, f func(c *C)) *C {
+	if atomic.LoadUint32(&parent.finished) != 0 {
+		// The parent timed out and was already reported, so stop the
+		// test left behind rather than running more of it.
+		parent.stopNow()
+	}
+	depth := parent.depth + 1
+	if depth <= len(runner.subFilters) {
+		if re := runner.subFilters[depth-1]; re != nil && !re.MatchString(name) {
+			return nil
+		}
+	}
+	scope := newCallScope(parent.scope.ctx)
+	c := runner.newCall(parent.method, testKd, parent.testName+"/"+name, nil, scope)
+	c.parent = parent
+	c.depth = depth
+	c.iteration = parent.iteration
+	c.muted = parent.muted
+	return runner.runNested(parent, c, func(c *C) {
+		defer func() {
+			if c.status() != timedOutSt {
+				scope.runCleanups(c, nil)
+			}
+		}()
+		defer scope.cancel()
+		defer c.StopTimer()
+		c.ResetTimer()
+		c.StartTimer()
+		f(c)
+	})
 }
 
 // Same as forkTest(), but wait for the test to finish before returning.
-func (runner *suiteRunner) runTest(method *methodType) *C {
-	c := runner.forkTest(method)
-	<-c.done
+// Tests which call Parallel are only waited for until they get paused,
+// and are then collected to be waited for by waitParallel().
+func (runner *suiteRunner) runTest(method *methodType, iteration int, muted bool) *C {
+	c := runner.forkTest(method, iteration, muted)
+	select {
+	case c = <-c.done: // May be a later attempt, if the test was retried.
+	case <-runner.parallelPaused:
+		runner.parallelTests = append(runner.parallelTests, c)
+	}
 	return c
 }
 
+// Run the test once, or repeatedly as requested by RunConf.Count and
+// RunConf.UntilFail, and return the call of the last iteration run.
+func (runner *suiteRunner) repeatTest(method *methodType) *C {
+	if runner.count == 1 && !runner.untilFail {
+		return runner.runTest(method, 0, false)
+	}
+	var c *C
+	var counts RepeatCounts
+	for i := 1; i <= runner.count || runner.untilFail && runner.count == 1; i++ {
+		c = runner.runTest(method, i, counts.Failed > 0)
+		switch c.status() {
+		case succeededSt:
+			counts.Succeeded++
+			continue
+		case skippedSt, missedSt:
+			// Further iterations won't do any better.
+		default:
+			counts.Failed++
+			if counts.FirstFailure == 0 {
+				counts.FirstFailure = i
+			}
+			if c.status() != fixturePanickedSt && !runner.untilFail && !runner.aborted() {
+				continue
+			}
+		}
+		break
+	}
+	if runner.repeats == nil {
+		runner.repeats = make(map[string]RepeatCounts)
+	}
+	runner.repeats[method.String()] = counts
+	return c
+}
+
+// Resume the tests paused by Parallel and wait for them to finish.
+func (runner *suiteRunner) waitParallel() {
+	close(runner.parallelResume)
+	for _, c := range runner.parallelTests {
+		<-c.done
+	}
+	runner.parallelTests = nil
+}
+
+// Run the cleanups registered within the suite fixtures. They're run as
+// calls of the fixtures which registered them, so that any panics get
+// reported against those.
+func (runner *suiteRunner) runSuiteCleanups() {
+	scope := runner.suiteScope
+	for {
+		method := scope.lastCleanupMethod()
+		if method == nil {
+			return
+		}
+		runner.runFunc(method, fixtureKd, "", nil, func(c *C) {
+			scope.runCleanups(c, method)
+		})
+	}
+}
+
+// Run the cleanups registered within the test call c. Those registered
+// by SetUpTest or TearDownTest are run as calls of those fixtures, so
+// that any panics get reported against them and the test is reported
+// as it would be if the fixture itself had panicked.
+func (runner *suiteRunner) runTestCleanups(c *C) {
+	scope := c.scope
+	var fixtureFailure *fixturePanic
+	for {
+		method := scope.lastCleanupMethod()
+		if method == nil {
+			break
+		}
+		if method != runner.setUpTest && method != runner.tearDownTest {
+			scope.runCleanups(c, method)
+			continue
+		}
+		fixture := runner.newCall(method, fixtureKd, c.testName, nil, scope)
+		runner.runNested(c, fixture, func(fixture *C) {
+			scope.runCleanups(fixture, method)
+		})
+		if fixture.status() != succeededSt && fixtureFailure == nil {
+			fixtureFailure = &fixturePanic{fixture.status(), method}
+		}
+	}
+	if fixtureFailure != nil {
+		panic(fixtureFailure)
+	}
+}
+
 // Helper to mark tests as skipped or missed.  A bit heavy for what
 // it does, but it enables homogeneous handling of tracking, including
 // nice verbose output.
@@ -846,31 +1824,48 @@ func (runner *suiteRunner) checkFixtureArgs() bool {
 }
 
 func (runner *suiteRunner) reportCallStarted(c *C) {
-	runner.output.WriteCallStarted("START", c)
+	call := c.callInfo()
+	runner.state.callStarted(call)
+	runner.output.CallStarted(call)
 }
 
 func (runner *suiteRunner) reportCallDone(c *C) {
+	result := c.callResult()
+	runner.state.callDone(result)
+	if c.kind == testKd {
+		runner.addTestResult(c, result)
+	}
 	runner.tracker.callDone(c)
-	switch c.status() {
-	case succeededSt:
-		if c.mustFail {
-			runner.output.WriteCallSuccess("FAIL EXPECTED", c)
-		} else {
-			runner.output.WriteCallSuccess("PASS", c)
-		}
-	case skippedSt:
-		runner.output.WriteCallSuccess("SKIP", c)
-	case failedSt:
-		runner.output.WriteCallProblem("FAIL", c)
-	case panickedSt:
-		runner.output.WriteCallProblem("PANIC", c)
-	case fixturePanickedSt:
-		// That's a testKd call reporting that its fixture
-		// has panicked. The fixture call which caused the
-		// panic itself was tracked above. We'll report to
-		// aid debugging.
-		runner.output.WriteCallProblem("PANIC", c)
-	case missedSt:
-		runner.output.WriteCallSuccess("MISS", c)
+	if runner.failFast {
+		switch c.status() {
+		case failedSt, panickedSt, timedOutSt:
+			runner.state.stop()
+		}
+	}
+	if runner.testingT && c.kind == testKd && c.parent == nil {
+		// Reported to the testing package by runT.
+		return
+	}
+	if c.muted && c.status() != succeededSt && c.status() != skippedSt {
+		// Only the first failing iteration of a test is reported.
+		return
+	}
+	runner.output.CallFinished(result)
+}
+
+func (runner *suiteRunner) addTestResult(c *C, result *CallResult) {
+	test := TestResult{CallResult: *result}
+	test.logb = nil
+	if c.N > 0 && result.Status == CallSucceeded {
+		test.Benchmark = &testing.BenchmarkResult{
+			N:         c.N,
+			T:         c.duration,
+			Bytes:     c.bytes,
+			MemAllocs: c.netAllocs,
+			MemBytes:  c.netBytes,
+		}
 	}
+	runner.testResultsm.Lock()
+	runner.testResults = append(runner.testResults, test)
+	runner.testResultsm.Unlock()
 }
@@ -6,6 +6,7 @@ package check_test
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"runtime"
@@ -91,6 +92,55 @@ func (s *SuccessHelper) TestLogAndSucceed(c *check.C) {
 	c.Log("Expected success!")
 }
 
+// -----------------------------------------------------------------------
+// Helper suite with a test for each outcome, for testing the reports.
+
+type OutcomeHelper struct {
+	failLine  int
+	panicLine int
+}
+
+func (s *OutcomeHelper) SetUpTest(c *check.C) {}
+
+func (s *OutcomeHelper) TestExpectFailure(c *check.C) {
+	c.ExpectFailure("Bug #1")
+	c.Fail()
+}
+
+func (s *OutcomeHelper) TestFail(c *check.C) {
+	c.Log("Failing")
+	s.failLine = getMyLine() + 1
+	c.Check(1, check.Equals, 2)
+}
+
+func (s *OutcomeHelper) TestPanic(c *check.C) {
+	s.panicLine = getMyLine() + 1
+	panic("Boom!")
+}
+
+func (s *OutcomeHelper) TestPass(c *check.C) {
+	c.Log("Passing")
+}
+
+func (s *OutcomeHelper) TestSkip(c *check.C) {
+	c.Skip("Not now")
+}
+
+// Run the suites with the given configuration, returning the result
+// along with the output, which the tests of other reports may ignore.
+func runCapturing(runConf *check.RunConf, suites ...interface{}) (*check.Result, string) {
+	output := String{}
+	runConf.Output = &output
+	return check.RunSuites(suites, runConf), output.value
+}
+
+// Return the content of a report written to a file.
+func readReport(c *check.C, path string) string {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	return string(data)
+}
+
 // -----------------------------------------------------------------------
 // Helper suite for testing ordering and behavior of fixture.
 
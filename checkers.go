@@ -2,9 +2,12 @@ package check
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/kr/pretty"
 )
@@ -202,7 +205,11 @@ func formatUnequal(obtained interface{}, expected interface{}) string {
 	}
 
 	// generic diff
-	diff := pretty.Diff(obtained, expected)
+	return formatDifference(pretty.Diff(obtained, expected))
+}
+
+// formatDifference returns the lines of a diff as an error message.
+func formatDifference(diff []string) string {
 	if len(diff) == 0 {
 		// No diff, this happens when e.g. just struct
 		// pointers are different but the structs have
@@ -273,6 +280,376 @@ func (checker *deepEqualsChecker) Check(params []interface{}, names []string) (r
 	return
 }
 
+// -----------------------------------------------------------------------
+// DeepEqualsWith checker.
+
+// A DeepEqualsOption changes how DeepEqualsWith compares values.
+type DeepEqualsOption func(*deepEqualsOptions)
+
+type deepEqualsOptions struct {
+	ignoreFields     []string
+	ignoreUnexported bool
+	equateEmpty      bool
+	sorters          map[reflect.Type]reflect.Value
+	comparers        map[reflect.Type]reflect.Value
+	problem          string
+}
+
+// IgnoreFields makes DeepEqualsWith skip the named struct fields. A
+// plain name such as "UpdatedAt" skips the fields with that name in
+// any struct, while a dotted path such as "Owner.ID" only skips the
+// field reached through those field names from the compared values.
+// Slice and array indexes, map keys and pointers are not part of
+// paths, so "Items.ID" skips the ID field of every element of Items.
+func IgnoreFields(names ...string) DeepEqualsOption {
+	return func(options *deepEqualsOptions) {
+		options.ignoreFields = append(options.ignoreFields, names...)
+	}
+}
+
+// IgnoreUnexported makes DeepEqualsWith skip unexported struct fields.
+// Values of struct types without exported fields, such as time.Time or
+// the errors created by errors.New, are still compared as a whole, the
+// time.Time ones with their Equal method.
+func IgnoreUnexported() DeepEqualsOption {
+	return func(options *deepEqualsOptions) {
+		options.ignoreUnexported = true
+	}
+}
+
+// EquateEmpty makes DeepEqualsWith consider nil and empty slices or
+// maps equal.
+func EquateEmpty() DeepEqualsOption {
+	return func(options *deepEqualsOptions) {
+		options.equateEmpty = true
+	}
+}
+
+// SortSlices makes DeepEqualsWith sort the slices of type []T before
+// comparing them, given a less function of type func(a, b T) bool, so
+// that the order of their elements doesn't matter. Differences are
+// then reported at the sorted positions.
+func SortSlices(less interface{}) DeepEqualsOption {
+	return func(options *deepEqualsOptions) {
+		t, ok := binaryFuncType(less)
+		if !ok {
+			options.problem = fmt.Sprintf("SortSlices needs a func(T, T) bool, got %T", less)
+			return
+		}
+		if options.sorters == nil {
+			options.sorters = make(map[reflect.Type]reflect.Value)
+		}
+		options.sorters[t] = reflect.ValueOf(less)
+	}
+}
+
+// Comparer makes DeepEqualsWith compare the values of type T with the
+// provided function of type func(a, b T) bool rather than comparing
+// their contents. Values reached through unexported fields can't be
+// passed to the function, and are compared as usual.
+func Comparer(equal interface{}) DeepEqualsOption {
+	return func(options *deepEqualsOptions) {
+		t, ok := binaryFuncType(equal)
+		if !ok {
+			options.problem = fmt.Sprintf("Comparer needs a func(T, T) bool, got %T", equal)
+			return
+		}
+		if options.comparers == nil {
+			options.comparers = make(map[reflect.Type]reflect.Value)
+		}
+		options.comparers[t] = reflect.ValueOf(equal)
+	}
+}
+
+// Return T for a function of type func(a, b T) bool.
+func binaryFuncType(f interface{}) (reflect.Type, bool) {
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 1 ||
+		t.In(0) != t.In(1) || t.Out(0).Kind() != reflect.Bool || reflect.ValueOf(f).IsNil() {
+		return nil, false
+	}
+	return t.In(0), true
+}
+
+type deepEqualsWithChecker struct {
+	*CheckerInfo
+	options deepEqualsOptions
+}
+
+// The DeepEqualsWith checker verifies that the obtained value is
+// deep-equal to the expected value, as DeepEquals does, with the
+// comparison adjusted by the provided options. On failure, the path
+// of every differing value is reported. Functions are only equal when
+// nil, unless a Comparer is given for their type.
+//
+// For example:
+//
+//     c.Assert(user, DeepEqualsWith(IgnoreFields("ID", "CreatedAt")), expected)
+//     c.Assert(tags, DeepEqualsWith(SortSlices(func(a, b string) bool { return a < b })), expected)
+//
+func DeepEqualsWith(options ...DeepEqualsOption) Checker {
+	checker := &deepEqualsWithChecker{
+		CheckerInfo: &CheckerInfo{Name: "DeepEqualsWith", Params: []string{"obtained", "expected"}},
+	}
+	for _, option := range options {
+		option(&checker.options)
+	}
+	return checker
+}
+
+func (checker *deepEqualsWithChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	if checker.options.problem != "" {
+		return false, checker.options.problem
+	}
+	d := deepDiffer{options: &checker.options, visited: make(deepVisits)}
+	d.diff(reflect.ValueOf(params[0]), reflect.ValueOf(params[1]), "", "")
+	if len(d.lines) == 0 {
+		return true, ""
+	}
+	return false, formatDifference(d.lines)
+}
+
+// Comparison of values reporting their differences in the same
+// format as pretty.Diff.
+type deepDiffer struct {
+	options *deepEqualsOptions
+	visited deepVisits
+	lines   []string
+}
+
+type deepVisit struct {
+	a, b uintptr
+	n    int // Length of slices, which share their data with longer ones.
+	t    reflect.Type
+}
+
+// The pairs of values compared so far, through which values may refer
+// to themselves.
+type deepVisits map[deepVisit]bool
+
+// Record the comparison of x and y, which are of the same type, and
+// tell whether they were compared already. Only maps, slices, pointers
+// and addressable interfaces are recorded, as reflect.DeepEqual does,
+// since any cycle goes through one of them.
+func (visits deepVisits) seen(x, y reflect.Value) bool {
+	var visit deepVisit
+	switch x.Kind() {
+	case reflect.Map, reflect.Ptr:
+		visit = deepVisit{a: x.Pointer(), b: y.Pointer(), t: x.Type()}
+	case reflect.Slice:
+		visit = deepVisit{a: x.Pointer(), b: y.Pointer(), n: x.Len(), t: x.Type()}
+	case reflect.Interface:
+		if !x.CanAddr() || !y.CanAddr() {
+			return false
+		}
+		visit = deepVisit{a: x.UnsafeAddr(), b: y.UnsafeAddr(), t: x.Type()}
+	default:
+		return false
+	}
+	if x.IsNil() || y.IsNil() {
+		return false
+	}
+	if visits[visit] {
+		return true
+	}
+	visits[visit] = true
+	return false
+}
+
+func (d *deepDiffer) printf(label, format string, args ...interface{}) {
+	if label != "" {
+		format = label + ": " + format
+	}
+	d.lines = append(d.lines, fmt.Sprintf(format, args...))
+}
+
+func relabel(label, name string) string {
+	if label != "" && name[0] != '[' {
+		label += "."
+	}
+	return label + name
+}
+
+func (d *deepDiffer) ignoreField(fieldPath, name string) bool {
+	for _, ignored := range d.options.ignoreFields {
+		if ignored == name || ignored == fieldPath {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff av and bv, where label is the path of the values as shown in
+// differences and fieldPath the struct field names leading to them.
+func (d *deepDiffer) diff(av, bv reflect.Value, label, fieldPath string) {
+	if !av.IsValid() || !bv.IsValid() {
+		if av.IsValid() {
+			d.printf(label, "%#v != nil", av)
+		} else if bv.IsValid() {
+			d.printf(label, "nil != %#v", bv)
+		}
+		return
+	}
+	t := av.Type()
+	if t != bv.Type() {
+		d.printf(label, "%v != %v", t, bv.Type())
+		return
+	}
+	if equal, ok := d.options.comparers[t]; ok && av.CanInterface() && bv.CanInterface() {
+		if !equal.Call([]reflect.Value{av, bv})[0].Bool() {
+			d.printf(label, "%#v != %#v", av, bv)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if a, b := av.Bool(), bv.Bool(); a != b {
+			d.printf(label, "%v != %v", a, b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a, b := av.Int(), bv.Int(); a != b {
+			d.printf(label, "%d != %d", a, b)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a, b := av.Uint(), bv.Uint(); a != b {
+			d.printf(label, "%d != %d", a, b)
+		}
+	case reflect.Float32, reflect.Float64:
+		if a, b := av.Float(), bv.Float(); a != b {
+			d.printf(label, "%v != %v", a, b)
+		}
+	case reflect.Complex64, reflect.Complex128:
+		if a, b := av.Complex(), bv.Complex(); a != b {
+			d.printf(label, "%v != %v", a, b)
+		}
+	case reflect.String:
+		if a, b := av.String(), bv.String(); a != b {
+			d.printf(label, "%q != %q", a, b)
+		}
+	case reflect.Chan, reflect.UnsafePointer:
+		if a, b := av.Pointer(), bv.Pointer(); a != b {
+			d.printf(label, "%#x != %#x", a, b)
+		}
+	case reflect.Func:
+		// As with DeepEquals, only nil functions are equal.
+		if !av.IsNil() || !bv.IsNil() {
+			d.printf(label, "Cannot compare non-nil %s without a Comparer", t)
+		}
+	case reflect.Interface:
+		if !d.visited.seen(av, bv) {
+			d.diff(av.Elem(), bv.Elem(), label, fieldPath)
+		}
+	case reflect.Ptr:
+		switch {
+		case av.IsNil() && bv.IsNil():
+		case av.IsNil():
+			d.printf(label, "nil != %#v", bv)
+		case bv.IsNil():
+			d.printf(label, "%#v != nil", av)
+		case !d.visited.seen(av, bv):
+			d.diff(av.Elem(), bv.Elem(), label, fieldPath)
+		}
+	case reflect.Struct:
+		if d.options.ignoreUnexported && !hasExportedFields(t) && av.CanInterface() && bv.CanInterface() {
+			if !opaqueEqual(av.Interface(), bv.Interface()) {
+				d.printf(label, "%#v != %#v", av, bv)
+			}
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			path := relabel(fieldPath, field.Name)
+			if field.PkgPath != "" && d.options.ignoreUnexported || d.ignoreField(path, field.Name) {
+				continue
+			}
+			d.diff(av.Field(i), bv.Field(i), relabel(label, field.Name), path)
+		}
+	case reflect.Array:
+		for i := 0; i < av.Len(); i++ {
+			d.diff(av.Index(i), bv.Index(i), relabel(label, fmt.Sprintf("[%d]", i)), fieldPath)
+		}
+	case reflect.Slice:
+		if d.emptyMismatch(av, bv, label) {
+			return
+		}
+		if av.Len() != bv.Len() {
+			d.printf(label, "%s[%d] != %s[%d]", t, av.Len(), t, bv.Len())
+			return
+		}
+		if d.visited.seen(av, bv) {
+			return
+		}
+		aOrder, bOrder := d.sortedIndexes(av), d.sortedIndexes(bv)
+		for i := 0; i < av.Len(); i++ {
+			d.diff(av.Index(aOrder[i]), bv.Index(bOrder[i]), relabel(label, fmt.Sprintf("[%d]", i)), fieldPath)
+		}
+	case reflect.Map:
+		if d.emptyMismatch(av, bv, label) || d.visited.seen(av, bv) {
+			return
+		}
+		for _, key := range sortedKeys(av) {
+			keyLabel := relabel(label, fmt.Sprintf("[%#v]", key))
+			if bv.MapIndex(key).IsValid() {
+				d.diff(av.MapIndex(key), bv.MapIndex(key), keyLabel, fieldPath)
+			} else {
+				d.printf(keyLabel, "%#v != (missing)", av.MapIndex(key))
+			}
+		}
+		for _, key := range sortedKeys(bv) {
+			if !av.MapIndex(key).IsValid() {
+				d.printf(relabel(label, fmt.Sprintf("[%#v]", key)), "(missing) != %#v", bv.MapIndex(key))
+			}
+		}
+	}
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare values which can't be compared field by field.
+func opaqueEqual(a, b interface{}) bool {
+	if at, ok := a.(time.Time); ok {
+		return at.Equal(b.(time.Time))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Report a nil slice or map compared to a non-nil empty one, unless
+// EquateEmpty was used, and tell whether there's nothing else to diff.
+func (d *deepDiffer) emptyMismatch(av, bv reflect.Value, label string) bool {
+	if av.Len() != 0 || bv.Len() != 0 {
+		return false
+	}
+	if av.IsNil() != bv.IsNil() && !d.options.equateEmpty {
+		d.printf(label, "%#v != %#v", av, bv)
+	}
+	return true
+}
+
+// Return the indexes of the slice elements in the order set by
+// SortSlices, if any was provided for their type.
+func (d *deepDiffer) sortedIndexes(v reflect.Value) []int {
+	indexes := make([]int, v.Len())
+	for i := range indexes {
+		indexes[i] = i
+	}
+	less, ok := d.options.sorters[v.Type().Elem()]
+	if !ok || !v.CanInterface() {
+		return indexes
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return less.Call([]reflect.Value{v.Index(indexes[i]), v.Index(indexes[j])})[0].Bool()
+	})
+	return indexes
+}
+
 // -----------------------------------------------------------------------
 // HasLen checker.
 
@@ -339,6 +716,179 @@ func (checker errorMatchesChecker) Check(params []interface{}, names []string) (
 	return matches(params[0], params[1])
 }
 
+// -----------------------------------------------------------------------
+// ErrorIs, ErrorAs and ErrorContains checkers.
+
+type errorIsChecker struct {
+	*CheckerInfo
+}
+
+// The ErrorIs checker verifies that the error value is non nil and that
+// some error in its chain, as unwrapped by the Unwrap method, is equal
+// to the target error or reports being so through an Is method, as
+// done by errors.Is. On failure the chain is logged one error per line.
+//
+// For example:
+//
+//     c.Assert(err, ErrorIs, os.ErrNotExist)
+//
+var ErrorIs Checker = &errorIsChecker{
+	&CheckerInfo{Name: "ErrorIs", Params: []string{"value", "target"}},
+}
+
+func (checker *errorIsChecker) Check(params []interface{}, names []string) (result bool, errStr string) {
+	err, problem := errorValue(params[0])
+	if problem != "" {
+		return false, problem
+	}
+	target, ok := params[1].(error)
+	if !ok {
+		return false, "Target is not an error"
+	}
+	comparable := reflect.TypeOf(target).Comparable()
+	found := walkErrors(err, 0, func(err error, depth int) bool {
+		if comparable && err == target {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return true
+		}
+		return false
+	})
+	if !found {
+		return false, "No error in the chain matches the target\n" + errorChain(err)
+	}
+	return true, ""
+}
+
+type errorAsChecker struct {
+	*CheckerInfo
+}
+
+// The ErrorAs checker verifies that the error value is non nil and that
+// some error in its chain can be assigned to the value pointed to by
+// target, which is then set to that error as done by errors.As. The
+// target must be a non-nil pointer to an interface or to a type
+// implementing error. On failure the chain is logged one error per line.
+//
+// For example:
+//
+//     var pathErr *os.PathError
+//     c.Assert(err, ErrorAs, &pathErr)
+//     c.Assert(pathErr.Path, Equals, "/tmp/missing")
+//
+var ErrorAs Checker = &errorAsChecker{
+	&CheckerInfo{Name: "ErrorAs", Params: []string{"value", "target"}},
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func (checker *errorAsChecker) Check(params []interface{}, names []string) (result bool, errStr string) {
+	err, problem := errorValue(params[0])
+	if problem != "" {
+		return false, problem
+	}
+	target := reflect.ValueOf(params[1])
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return false, "Target must be a non-nil pointer"
+	}
+	targetType := target.Type().Elem()
+	if targetType.Kind() != reflect.Interface && !targetType.Implements(errorType) {
+		return false, "Target must point to an interface or to a type implementing error"
+	}
+	found := walkErrors(err, 0, func(err error, depth int) bool {
+		if reflect.TypeOf(err).AssignableTo(targetType) {
+			target.Elem().Set(reflect.ValueOf(err))
+			return true
+		}
+		if x, ok := err.(interface{ As(interface{}) bool }); ok && x.As(params[1]) {
+			return true
+		}
+		return false
+	})
+	if !found {
+		return false, fmt.Sprintf("No error in the chain is assignable to %s\n%s", targetType, errorChain(err))
+	}
+	return true, ""
+}
+
+type errorContainsChecker struct {
+	*CheckerInfo
+}
+
+// The ErrorContains checker verifies that the error value is non nil
+// and that its message contains the provided substring. On failure the
+// chain is logged one error per line.
+//
+// For example:
+//
+//     c.Assert(err, ErrorContains, "permission denied")
+//
+var ErrorContains Checker = &errorContainsChecker{
+	&CheckerInfo{Name: "ErrorContains", Params: []string{"value", "substring"}},
+}
+
+func (checker *errorContainsChecker) Check(params []interface{}, names []string) (result bool, errStr string) {
+	err, problem := errorValue(params[0])
+	if problem != "" {
+		return false, problem
+	}
+	substring, ok := params[1].(string)
+	if !ok {
+		return false, "Substring must be a string"
+	}
+	if !strings.Contains(err.Error(), substring) {
+		return false, fmt.Sprintf("Error message doesn't contain %q\n%s", substring, errorChain(err))
+	}
+	return true, ""
+}
+
+func errorValue(value interface{}) (err error, problem string) {
+	if value == nil {
+		return nil, "Error value is nil"
+	}
+	err, ok := value.(error)
+	if !ok {
+		return nil, "Value is not an error"
+	}
+	return err, ""
+}
+
+// Walk the error chain depth-first, following both the Unwrap() error
+// and Unwrap() []error methods, until visit returns true.
+func walkErrors(err error, depth int, visit func(err error, depth int) bool) bool {
+	for err != nil {
+		if visit(err, depth) {
+			return true
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				if walkErrors(err, depth+1, visit) {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// Describe the error chain one error per line, indenting the errors
+// joined together by a single one.
+func errorChain(err error) string {
+	var lines []string
+	walkErrors(err, 0, func(err error, depth int) bool {
+		lines = append(lines, fmt.Sprintf("%s%T: %q", strings.Repeat("  ", depth), err, err.Error()))
+		return false
+	})
+	return fmt.Sprintf("Error chain:\n%s", formatMultiLine(strings.Join(lines, "\n"), false))
+}
+
 // -----------------------------------------------------------------------
 // Matches checker.
 
@@ -526,3 +1076,718 @@ func (checker *implementsChecker) Check(params []interface{}, names []string) (r
 	}
 	return obtained.Type().Implements(ifaceptr.Elem().Type()), ""
 }
+
+// -----------------------------------------------------------------------
+// Contains checker.
+
+type containsChecker struct {
+	*CheckerInfo
+}
+
+// The Contains checker verifies that the obtained value contains the
+// provided element. Slices and arrays must have an element deep-equal
+// to it, and maps a value deep-equal to it. Strings must contain it as
+// a substring, or as a rune. The values buffered in a channel are
+// looked into by receiving them and sending them back, so the channel
+// must not be closed or in use by other goroutines meanwhile.
+//
+// For example:
+//
+//     c.Assert(list, Contains, "item")
+//     c.Assert(output, Contains, "done")
+//
+var Contains Checker = &containsChecker{
+	&CheckerInfo{Name: "Contains", Params: []string{"obtained", "element"}},
+}
+
+func (checker *containsChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	value := reflect.ValueOf(params[0])
+	switch value.Kind() {
+	case reflect.String:
+		switch element := params[1].(type) {
+		case string:
+			return strings.Contains(value.String(), element), ""
+		case rune:
+			return strings.ContainsRune(value.String(), element), ""
+		}
+		return false, "element must be a string or a rune"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return indexOf(elements(value), params[1]) >= 0, ""
+	case reflect.Chan:
+		if value.Type().ChanDir() != reflect.BothDir {
+			return false, "obtained channel must be bidirectional"
+		}
+		buffered, problem := channelElements(value)
+		if problem != "" {
+			return false, problem
+		}
+		return indexOf(buffered, params[1]) >= 0, ""
+	}
+	return false, "obtained value type has no elements"
+}
+
+// Return the elements of a slice or array, or the values of a map.
+func elements(value reflect.Value) []interface{} {
+	var elements []interface{}
+	if value.Kind() == reflect.Map {
+		for _, key := range value.MapKeys() {
+			elements = append(elements, value.MapIndex(key).Interface())
+		}
+		return elements
+	}
+	for i := 0; i < value.Len(); i++ {
+		elements = append(elements, value.Index(i).Interface())
+	}
+	return elements
+}
+
+// Return the values buffered in the channel, by receiving them and
+// sending them back in order, or a problem if something else used the
+// channel meanwhile.
+func channelElements(ch reflect.Value) (elements []interface{}, problem string) {
+	var received []reflect.Value
+	for n := ch.Len(); n > 0; n-- {
+		value, ok := ch.TryRecv()
+		if !ok {
+			problem = "obtained channel was emptied while looking into it"
+			break
+		}
+		received = append(received, value)
+	}
+	defer func() {
+		if recover() != nil {
+			elements, problem = nil, "obtained channel was closed while looking into it, losing its values"
+		}
+	}()
+	for _, value := range received {
+		if !ch.TrySend(value) {
+			return nil, "obtained channel was filled while looking into it, losing its values"
+		}
+		elements = append(elements, value.Interface())
+	}
+	return elements, problem
+}
+
+// Return the index of the element deep-equal to the given one, or -1.
+func indexOf(elements []interface{}, element interface{}) int {
+	for i, e := range elements {
+		if reflect.DeepEqual(e, element) {
+			return i
+		}
+	}
+	return -1
+}
+
+// -----------------------------------------------------------------------
+// HasKey checker.
+
+type hasKeyChecker struct {
+	*CheckerInfo
+}
+
+// The HasKey checker verifies that the obtained map has the provided
+// key. For slices, arrays and strings, the key is an index which must
+// be within range.
+//
+// For example:
+//
+//     c.Assert(config, HasKey, "name")
+//
+var HasKey Checker = &hasKeyChecker{
+	&CheckerInfo{Name: "HasKey", Params: []string{"obtained", "key"}},
+}
+
+func (checker *hasKeyChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	value := reflect.ValueOf(params[0])
+	switch value.Kind() {
+	case reflect.Map:
+		key := reflect.ValueOf(params[1])
+		if !key.IsValid() {
+			key = reflect.Zero(value.Type().Key())
+		}
+		if !key.Type().AssignableTo(value.Type().Key()) {
+			return false, "key must be of type " + value.Type().Key().String()
+		}
+		return value.MapIndex(key).IsValid(), ""
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, ok := params[1].(int)
+		if !ok {
+			return false, "key must be an int index"
+		}
+		return i >= 0 && i < value.Len(), ""
+	}
+	return false, "obtained value type has no keys"
+}
+
+// -----------------------------------------------------------------------
+// ElementsMatch checker.
+
+type elementsMatchChecker struct {
+	*CheckerInfo
+}
+
+// The ElementsMatch checker verifies that the obtained slice or array
+// has the same elements as the expected one, in any order. Elements are
+// compared with reflect.DeepEqual, and repeated elements must be repeated
+// as many times in both. On failure the missing and extra elements are
+// listed.
+//
+// For example:
+//
+//     c.Assert(names, ElementsMatch, []string{"b", "a", "c"})
+//
+var ElementsMatch Checker = &elementsMatchChecker{
+	&CheckerInfo{Name: "ElementsMatch", Params: []string{"obtained", "expected"}},
+}
+
+func (checker *elementsMatchChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	obtained, expected := reflect.ValueOf(params[0]), reflect.ValueOf(params[1])
+	if !isList(obtained) {
+		return false, "obtained value must be a slice or an array"
+	}
+	if !isList(expected) {
+		return false, "expected value must be a slice or an array"
+	}
+	missing, extra := listDifference(elements(expected), elements(obtained))
+	if len(missing) == 0 && len(extra) == 0 {
+		return true, ""
+	}
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "Missing: "+formatElements(missing))
+	}
+	if len(extra) > 0 {
+		problems = append(problems, "Extra: "+formatElements(extra))
+	}
+	return false, formatDifference(problems)
+}
+
+func isList(value reflect.Value) bool {
+	return value.Kind() == reflect.Slice || value.Kind() == reflect.Array
+}
+
+// Return the elements of want missing from have, and those of have which
+// aren't in want, counting repeated elements.
+func listDifference(want, have []interface{}) (missing, extra []interface{}) {
+	extra = append(extra, have...)
+	for _, element := range want {
+		if i := indexOf(extra, element); i >= 0 {
+			extra = append(extra[:i], extra[i+1:]...)
+		} else {
+			missing = append(missing, element)
+		}
+	}
+	return missing, extra
+}
+
+func formatElements(elements []interface{}) string {
+	formatted := make([]string, len(elements))
+	for i, element := range elements {
+		formatted[i] = fmt.Sprintf("%#v", element)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// -----------------------------------------------------------------------
+// IsSubsetOf checker.
+
+type isSubsetOfChecker struct {
+	*CheckerInfo
+}
+
+// The IsSubsetOf checker verifies that all the elements of the obtained
+// slice or array are in the provided one, in any order, or that all the
+// keys of the obtained map are in the provided map with deep-equal values.
+// On failure the elements or keys which aren't are listed.
+//
+// For example:
+//
+//     c.Assert(features, IsSubsetOf, []string{"a", "b", "c"})
+//     c.Assert(labels, IsSubsetOf, map[string]string{"app": "web", "tier": "db"})
+//
+var IsSubsetOf Checker = &isSubsetOfChecker{
+	&CheckerInfo{Name: "IsSubsetOf", Params: []string{"obtained", "superset"}},
+}
+
+func (checker *isSubsetOfChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	obtained, superset := reflect.ValueOf(params[0]), reflect.ValueOf(params[1])
+	switch {
+	case isList(obtained) && isList(superset):
+		missing, _ := listDifference(elements(obtained), elements(superset))
+		if len(missing) > 0 {
+			return false, "Not in superset: " + formatElements(missing)
+		}
+		return true, ""
+	case obtained.Kind() == reflect.Map && superset.Kind() == reflect.Map:
+		if obtained.Type().Key() != superset.Type().Key() {
+			return false, "obtained and superset maps must have the same key type"
+		}
+		var problems []string
+		for _, key := range sortedKeys(obtained) {
+			value := superset.MapIndex(key)
+			if !value.IsValid() {
+				problems = append(problems, fmt.Sprintf("Key %#v not in superset", key.Interface()))
+			} else if o, s := obtained.MapIndex(key).Interface(), value.Interface(); !reflect.DeepEqual(o, s) {
+				problems = append(problems, fmt.Sprintf("Key %#v: %#v != %#v", key.Interface(), o, s))
+			}
+		}
+		if len(problems) > 0 {
+			return false, formatDifference(problems)
+		}
+		return true, ""
+	}
+	return false, "obtained and superset must both be slices or arrays, or both be maps"
+}
+
+// Return the keys of the map, sorted by their formatting for the sake
+// of stable messages.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%#v", keys[i]) < fmt.Sprintf("%#v", keys[j])
+	})
+	return keys
+}
+
+// -----------------------------------------------------------------------
+// Ordered comparison checkers.
+
+type orderChecker struct {
+	*CheckerInfo
+	accept func(cmp int) bool
+}
+
+// The GreaterThan checker verifies that the obtained value is greater
+// than the expected one. Values may be integers, unsigned integers,
+// floats, strings, time.Time and time.Duration, and both must be of the
+// same type.
+//
+// For example:
+//
+//     c.Assert(len(items), GreaterThan, 0)
+//     c.Assert(elapsed, GreaterThan, time.Second)
+//
+var GreaterThan Checker = &orderChecker{
+	&CheckerInfo{Name: "GreaterThan", Params: []string{"obtained", "expected"}},
+	func(cmp int) bool { return cmp > 0 },
+}
+
+// The GreaterOrEqual checker verifies that the obtained value is greater
+// than or equal to the expected one. It accepts the same types as
+// GreaterThan.
+//
+// For example:
+//
+//     c.Assert(version, GreaterOrEqual, "1.2")
+//
+var GreaterOrEqual Checker = &orderChecker{
+	&CheckerInfo{Name: "GreaterOrEqual", Params: []string{"obtained", "expected"}},
+	func(cmp int) bool { return cmp >= 0 },
+}
+
+// The LessThan checker verifies that the obtained value is less than the
+// expected one. It accepts the same types as GreaterThan.
+//
+// For example:
+//
+//     c.Assert(latency, LessThan, 100*time.Millisecond)
+//
+var LessThan Checker = &orderChecker{
+	&CheckerInfo{Name: "LessThan", Params: []string{"obtained", "expected"}},
+	func(cmp int) bool { return cmp < 0 },
+}
+
+// The LessOrEqual checker verifies that the obtained value is less than
+// or equal to the expected one. It accepts the same types as GreaterThan.
+//
+// For example:
+//
+//     c.Assert(created, LessOrEqual, time.Now())
+//
+var LessOrEqual Checker = &orderChecker{
+	&CheckerInfo{Name: "LessOrEqual", Params: []string{"obtained", "expected"}},
+	func(cmp int) bool { return cmp <= 0 },
+}
+
+func (checker *orderChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	cmp, error := compareOrdered(params[0], params[1], names[0], names[1])
+	if error != "" {
+		return false, error
+	}
+	return checker.accept(cmp), ""
+}
+
+// -----------------------------------------------------------------------
+// Between checker.
+
+type betweenChecker struct {
+	*CheckerInfo
+}
+
+// The Between checker verifies that the obtained value is within the
+// provided range, including both its ends. It accepts the same types as
+// GreaterThan.
+//
+// For example:
+//
+//     c.Assert(ratio, Between, 0.5, 0.75)
+//
+var Between Checker = &betweenChecker{
+	&CheckerInfo{Name: "Between", Params: []string{"obtained", "min", "max"}},
+}
+
+func (checker *betweenChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	cmp, error := compareOrdered(params[1], params[2], names[1], names[2])
+	if error != "" {
+		return false, error
+	}
+	if cmp > 0 {
+		return false, names[1] + " is greater than " + names[2]
+	}
+	minCmp, error := compareOrdered(params[0], params[1], names[0], names[1])
+	if error != "" {
+		return false, error
+	}
+	maxCmp, _ := compareOrdered(params[0], params[2], names[0], names[2])
+	return minCmp >= 0 && maxCmp <= 0, ""
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Compare two values of the same ordered type, returning -1, 0 or 1 as
+// a is less than, equal to, or greater than b.
+func compareOrdered(a, b interface{}, aName, bName string) (cmp int, error string) {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !av.IsValid() || !isOrdered(av.Type()) {
+		return 0, aName + " value type is not ordered"
+	}
+	if !bv.IsValid() || bv.Type() != av.Type() {
+		return 0, fmt.Sprintf("%s must be of type %s, as %s is", bName, av.Type(), aName)
+	}
+	switch av.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareValues(av.Int() < bv.Int(), av.Int() > bv.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareValues(av.Uint() < bv.Uint(), av.Uint() > bv.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		af, bf := av.Float(), bv.Float()
+		if math.IsNaN(af) || math.IsNaN(bf) {
+			return 0, "NaN can't be ordered"
+		}
+		return compareValues(af < bf, af > bf), ""
+	case reflect.String:
+		return strings.Compare(av.String(), bv.String()), ""
+	}
+	at, bt := a.(time.Time), b.(time.Time)
+	return compareValues(at.Before(bt), at.After(bt)), ""
+}
+
+func isOrdered(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return t == timeType
+}
+
+func compareValues(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// -----------------------------------------------------------------------
+// EqualsWithin and EqualsRelative checkers.
+
+type approxChecker struct {
+	*CheckerInfo
+	relative bool
+}
+
+// The EqualsWithin checker verifies that the obtained value is equal to
+// the expected one within the provided absolute tolerance. Numbers of
+// any kind, including time.Duration, are compared with the tolerance,
+// as are the numbers within slices, arrays, maps, structs and pointers,
+// while other values must be equal, with time.Time values compared by
+// their Equal method, which can't be called on unexported fields. NaN is only equal to NaN, and an infinity only to
+// the same infinity.
+//
+// For example:
+//
+//     c.Assert(mean, EqualsWithin, 2.5, 1e-9)
+//     c.Assert(point, EqualsWithin, Point{1, 2}, 0.001)
+//     c.Assert(elapsed, EqualsWithin, time.Second, 10*time.Millisecond)
+//
+var EqualsWithin Checker = &approxChecker{
+	&CheckerInfo{Name: "EqualsWithin", Params: []string{"obtained", "expected", "tolerance"}},
+	false,
+}
+
+// The EqualsRelative checker verifies that the obtained value is equal
+// to the expected one within the provided relative epsilon, so that the
+// difference of two numbers is at most epsilon times the larger of their
+// absolute values. It accepts the same values as EqualsWithin.
+//
+// For example:
+//
+//     c.Assert(total, EqualsRelative, 1e6, 1e-6)
+//
+var EqualsRelative Checker = &approxChecker{
+	&CheckerInfo{Name: "EqualsRelative", Params: []string{"obtained", "expected", "epsilon"}},
+	true,
+}
+
+func (checker *approxChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	tolerance, ok := toFloat(params[2])
+	if !ok || tolerance < 0 || math.IsNaN(tolerance) {
+		return false, names[2] + " must be a non-negative number"
+	}
+	obtained, expected := reflect.ValueOf(params[0]), reflect.ValueOf(params[1])
+	if !obtained.IsValid() || !expected.IsValid() {
+		return obtained.IsValid() == expected.IsValid(), ""
+	}
+	if obtained.Type() != expected.Type() {
+		return false, fmt.Sprintf("%s must be of type %s, as %s is", names[1], obtained.Type(), names[0])
+	}
+	a := approx{tolerance: tolerance, relative: checker.relative, visited: make(deepVisits)}
+	if a.equal(obtained, expected, "") {
+		return true, ""
+	}
+	return false, a.problem
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// Approximate comparison of values of the same type, describing the
+// first difference found.
+type approx struct {
+	tolerance float64
+	relative  bool
+	problem   string
+	visited   deepVisits
+}
+
+func (a *approx) fail(path, format string, args ...interface{}) bool {
+	a.problem = fmt.Sprintf(format, args...)
+	if path != "" {
+		a.problem = "At " + path + ": " + a.problem
+	}
+	return false
+}
+
+func (a *approx) equal(x, y reflect.Value, path string) bool {
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, j := x.Int(), y.Int()
+		delta := uint64(i) - uint64(j)
+		if i < j {
+			delta = uint64(j) - uint64(i)
+		}
+		magnitude := math.Max(math.Abs(float64(i)), math.Abs(float64(j)))
+		if x.Type() == durationType {
+			return a.equalIntegers(time.Duration(i), time.Duration(j), time.Duration(delta), delta, magnitude, path)
+		}
+		return a.equalIntegers(i, j, delta, delta, magnitude, path)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, j := x.Uint(), y.Uint()
+		delta := i - j
+		if i < j {
+			delta = j - i
+		}
+		magnitude := math.Max(float64(i), float64(j))
+		return a.equalIntegers(i, j, delta, delta, magnitude, path)
+	case reflect.Float32, reflect.Float64:
+		return a.equalNumbers(x.Float(), y.Float(), path)
+	case reflect.Complex64, reflect.Complex128:
+		return a.equalNumbers(real(x.Complex()), real(y.Complex()), path+".real") &&
+			a.equalNumbers(imag(x.Complex()), imag(y.Complex()), path+".imag")
+	case reflect.Slice, reflect.Array:
+		if x.Len() != y.Len() {
+			return a.fail(path, "Length %d != %d", x.Len(), y.Len())
+		}
+		if a.visited.seen(x, y) {
+			return true
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !a.equal(x.Index(i), y.Index(i), fmt.Sprintf("%s[%d]", path, i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if x.Len() != y.Len() {
+			return a.fail(path, "Length %d != %d", x.Len(), y.Len())
+		}
+		if a.visited.seen(x, y) {
+			return true
+		}
+		for _, key := range sortedKeys(x) {
+			keyPath := fmt.Sprintf("%s[%#v]", path, key)
+			if !y.MapIndex(key).IsValid() {
+				return a.fail(keyPath, "Missing from expected")
+			}
+			if !a.equal(x.MapIndex(key), y.MapIndex(key), keyPath) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if x.Type() == timeType {
+			if !x.CanInterface() {
+				return a.fail(path, "Cannot compare unexported time.Time")
+			}
+			xt, yt := x.Interface().(time.Time), y.Interface().(time.Time)
+			if !xt.Equal(yt) {
+				return a.fail(path, "%v != %v", xt, yt)
+			}
+			return true
+		}
+		for i := 0; i < x.NumField(); i++ {
+			if !a.equal(x.Field(i), y.Field(i), path+"."+x.Type().Field(i).Name) {
+				return false
+			}
+		}
+		return true
+	case reflect.Ptr, reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				return a.fail(path, "Only one of the values is nil")
+			}
+			return true
+		}
+		if a.visited.seen(x, y) {
+			return true
+		}
+		x, y = x.Elem(), y.Elem()
+		if x.Type() != y.Type() {
+			return a.fail(path, "Type %s != %s", x.Type(), y.Type())
+		}
+		return a.equal(x, y, path)
+	case reflect.String:
+		if x.String() != y.String() {
+			return a.fail(path, "%q != %q", x.String(), y.String())
+		}
+		return true
+	case reflect.Bool:
+		if x.Bool() != y.Bool() {
+			return a.fail(path, "%v != %v", x.Bool(), y.Bool())
+		}
+		return true
+	}
+	// Functions, channels and unsafe pointers.
+	if x.Pointer() != y.Pointer() {
+		return a.fail(path, "Values differ")
+	}
+	return true
+}
+
+func (a *approx) equalNumbers(x, y float64, path string) bool {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		if math.IsNaN(x) != math.IsNaN(y) {
+			return a.fail(path, "%v != %v", x, y)
+		}
+		return true
+	}
+	if math.IsInf(x, 0) || math.IsInf(y, 0) {
+		if x != y {
+			return a.fail(path, "%v != %v", x, y)
+		}
+		return true
+	}
+	delta := math.Abs(x - y)
+	if a.relative {
+		limit := a.tolerance * math.Max(math.Abs(x), math.Abs(y))
+		if delta > limit {
+			return a.fail(path, "%g != %g (relative delta %.3g, epsilon %g)", x, y,
+				delta/math.Max(math.Abs(x), math.Abs(y)), a.tolerance)
+		}
+		return true
+	}
+	if delta > a.tolerance {
+		return a.fail(path, "%g != %g (delta %g, tolerance %g)", x, y, delta, a.tolerance)
+	}
+	return true
+}
+
+// Compare integers exactly, given their difference and the largest of
+// their absolute values, and report them as x and y.
+func (a *approx) equalIntegers(x, y, reportedDelta interface{}, delta uint64, magnitude float64, path string) bool {
+	if a.relative {
+		if float64(delta) > a.tolerance*magnitude {
+			return a.fail(path, "%v != %v (relative delta %.3g, epsilon %g)", x, y, float64(delta)/magnitude, a.tolerance)
+		}
+		return true
+	}
+	if a.tolerance < 1<<64 && delta > uint64(a.tolerance) {
+		tolerance := fmt.Sprintf("%g", a.tolerance)
+		if _, ok := x.(time.Duration); ok {
+			tolerance = time.Duration(a.tolerance).String()
+		}
+		return a.fail(path, "%v != %v (delta %v, tolerance %s)", x, y, reportedDelta, tolerance)
+	}
+	return true
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// -----------------------------------------------------------------------
+// TimeWithin checker.
+
+type timeWithinChecker struct {
+	*CheckerInfo
+}
+
+// The TimeWithin checker verifies that the obtained time.Time is within
+// the provided time.Duration of the expected one, before or after it.
+//
+// For example:
+//
+//     c.Assert(file.ModTime(), TimeWithin, time.Now(), time.Minute)
+//
+var TimeWithin Checker = &timeWithinChecker{
+	&CheckerInfo{Name: "TimeWithin", Params: []string{"obtained", "expected", "delta"}},
+}
+
+func (checker *timeWithinChecker) Check(params []interface{}, names []string) (result bool, error string) {
+	obtained, ok := params[0].(time.Time)
+	if !ok {
+		return false, names[0] + " value must be a time.Time"
+	}
+	expected, ok := params[1].(time.Time)
+	if !ok {
+		return false, names[1] + " value must be a time.Time"
+	}
+	delta, ok := params[2].(time.Duration)
+	if !ok || delta < 0 {
+		return false, names[2] + " must be a non-negative time.Duration"
+	}
+	if !obtained.Before(expected.Add(-delta)) && !obtained.After(expected.Add(delta)) {
+		return true, ""
+	}
+	difference := obtained.Sub(expected)
+	if difference < 0 {
+		difference = -difference
+	}
+	if difference < 0 || difference == math.MaxInt64 {
+		// Sub saturates for times about 292 years apart.
+		return false, fmt.Sprintf("Difference of over %s is more than %s", time.Duration(math.MaxInt64), delta)
+	}
+	return false, fmt.Sprintf("Difference of %s is more than %s", difference, delta)
+}
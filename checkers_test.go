@@ -2,8 +2,12 @@ package check_test
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"reflect"
 	"runtime"
+	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 )
@@ -176,6 +180,103 @@ func (s *CheckersS) TestErrorMatches(c *check.C) {
 	c.Assert(names[0], check.Equals, "error")
 }
 
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e *wrappedError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *wrappedError) Unwrap() error { return e.err }
+
+type joinedErrors []error
+
+func (e joinedErrors) Error() string   { return "joined" }
+func (e joinedErrors) Unwrap() []error { return e }
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string { return "code error" }
+
+type anyCodeError struct{}
+
+func (e anyCodeError) Error() string { return "any code" }
+
+func (e anyCodeError) Is(target error) bool {
+	_, ok := target.(*codeError)
+	return ok
+}
+
+var errNotFound = errors.New("not found")
+
+func (s *CheckersS) TestErrorIs(c *check.C) {
+	testInfo(c, check.ErrorIs, "ErrorIs", []string{"value", "target"})
+
+	wrapped := &wrappedError{"open", &wrappedError{"read", errNotFound}}
+	testCheck(c, check.ErrorIs, true, "", errNotFound, errNotFound)
+	testCheck(c, check.ErrorIs, true, "", wrapped, errNotFound)
+	testCheck(c, check.ErrorIs, true, "", joinedErrors{errors.New("other"), wrapped}, errNotFound)
+	testCheck(c, check.ErrorIs, true, "", &wrappedError{"open", anyCodeError{}}, &codeError{1})
+	testCheck(c, check.ErrorIs, false, "No error in the chain matches the target\n"+
+		"Error chain:\n"+
+		"...     *check_test.wrappedError: \"open: read: not found\"\n"+
+		"...     *check_test.wrappedError: \"read: not found\"\n"+
+		"...     *errors.errorString: \"not found\"\n", wrapped, errors.New("not found"))
+	testCheck(c, check.ErrorIs, false, "No error in the chain matches the target\n"+
+		"Error chain:\n"+
+		"...     check_test.joinedErrors: \"joined\"\n"+
+		"...       *errors.errorString: \"a\"\n"+
+		"...       *check_test.wrappedError: \"b: c\"\n"+
+		"...       *errors.errorString: \"c\"\n",
+		joinedErrors{errors.New("a"), &wrappedError{"b", errors.New("c")}}, errNotFound)
+
+	// Some bad values
+	testCheck(c, check.ErrorIs, false, "Error value is nil", nil, errNotFound)
+	testCheck(c, check.ErrorIs, false, "Value is not an error", 1, errNotFound)
+	testCheck(c, check.ErrorIs, false, "Target is not an error", errNotFound, nil)
+}
+
+func (s *CheckersS) TestErrorAs(c *check.C) {
+	testInfo(c, check.ErrorAs, "ErrorAs", []string{"value", "target"})
+
+	var codeErr *codeError
+	testCheck(c, check.ErrorAs, true, "", &wrappedError{"open", &codeError{3}}, &codeErr)
+	c.Assert(codeErr, check.NotNil)
+	c.Check(codeErr.code, check.Equals, 3)
+
+	var wrappedErr interface{ Unwrap() error }
+	testCheck(c, check.ErrorAs, true, "", joinedErrors{&wrappedError{"open", errNotFound}}, &wrappedErr)
+	c.Check(wrappedErr, check.FitsTypeOf, &wrappedError{})
+
+	testCheck(c, check.ErrorAs, false, "No error in the chain is assignable to *check_test.codeError\n"+
+		"Error chain:\n"+
+		"...     *check_test.wrappedError: \"open: not found\"\n"+
+		"...     *errors.errorString: \"not found\"\n", &wrappedError{"open", errNotFound}, &codeErr)
+
+	// Some bad values
+	testCheck(c, check.ErrorAs, false, "Error value is nil", nil, &codeErr)
+	testCheck(c, check.ErrorAs, false, "Target must be a non-nil pointer", errNotFound, codeError{})
+	testCheck(c, check.ErrorAs, false, "Target must be a non-nil pointer", errNotFound, (**codeError)(nil))
+	testCheck(c, check.ErrorAs, false, "Target must point to an interface or to a type implementing error", errNotFound, new(int))
+}
+
+func (s *CheckersS) TestErrorContains(c *check.C) {
+	testInfo(c, check.ErrorContains, "ErrorContains", []string{"value", "substring"})
+
+	testCheck(c, check.ErrorContains, true, "", &wrappedError{"open", errNotFound}, "not found")
+	testCheck(c, check.ErrorContains, true, "", &wrappedError{"open", errNotFound}, "open: not")
+	testCheck(c, check.ErrorContains, false, "Error message doesn't contain \"denied\"\n"+
+		"Error chain:\n"+
+		"...     *check_test.wrappedError: \"open: not found\"\n"+
+		"...     *errors.errorString: \"not found\"\n", &wrappedError{"open", errNotFound}, "denied")
+
+	// Some bad values
+	testCheck(c, check.ErrorContains, false, "Error value is nil", nil, "x")
+	testCheck(c, check.ErrorContains, false, "Value is not an error", "x", "x")
+	testCheck(c, check.ErrorContains, false, "Substring must be a string", errNotFound, 1)
+}
+
 func (s *CheckersS) TestMatches(c *check.C) {
 	testInfo(c, check.Matches, "Matches", []string{"value", "regex"})
 
@@ -287,3 +388,430 @@ func (s *CheckersS) TestImplements(c *check.C) {
 	testCheck(c, check.Implements, false, "ifaceptr should be a pointer to an interface variable", 0, interface{}(nil))
 	testCheck(c, check.Implements, false, "", interface{}(nil), &e)
 }
+
+func (s *CheckersS) TestContains(c *check.C) {
+	testInfo(c, check.Contains, "Contains", []string{"obtained", "element"})
+
+	// Slices, arrays and maps
+	testCheck(c, check.Contains, true, "", []int{1, 2, 3}, 2)
+	testCheck(c, check.Contains, false, "", []int{1, 2, 3}, 4)
+	testCheck(c, check.Contains, false, "", []int{1, 2, 3}, int64(2))
+	testCheck(c, check.Contains, true, "", [2]string{"a", "b"}, "b")
+	testCheck(c, check.Contains, true, "", []interface{}{nil, 1}, nil)
+	testCheck(c, check.Contains, true, "", [][]int{{1}, {2, 3}}, []int{2, 3})
+	testCheck(c, check.Contains, true, "", map[string]int{"a": 1}, 1)
+	testCheck(c, check.Contains, false, "", map[string]int{"a": 1}, "a")
+
+	// Strings
+	testCheck(c, check.Contains, true, "", "hello world", "o w")
+	testCheck(c, check.Contains, false, "", "hello world", "ow")
+	testCheck(c, check.Contains, true, "", "hello world", 'w')
+	testCheck(c, check.Contains, false, "element must be a string or a rune", "hello", 1.0)
+
+	// Channels
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	testCheck(c, check.Contains, true, "", ch, 2)
+	testCheck(c, check.Contains, false, "", ch, 3)
+	c.Assert(<-ch, check.Equals, 1)
+	c.Assert(<-ch, check.Equals, 2)
+	close(ch)
+	testCheck(c, check.Contains, false, "", ch, 1)
+	testCheck(c, check.Contains, false, "obtained channel must be bidirectional", (<-chan int)(ch), 1)
+	nils := make(chan interface{}, 2)
+	nils <- nil
+	nils <- 1
+	testCheck(c, check.Contains, true, "", nils, nil)
+	c.Assert(<-nils, check.IsNil)
+	c.Assert(<-nils, check.Equals, 1)
+
+	// Some bad values
+	testCheck(c, check.Contains, false, "obtained value type has no elements", 42, 42)
+	testCheck(c, check.Contains, false, "obtained value type has no elements", nil, 42)
+}
+
+func (s *CheckersS) TestHasKey(c *check.C) {
+	testInfo(c, check.HasKey, "HasKey", []string{"obtained", "key"})
+
+	testCheck(c, check.HasKey, true, "", map[string]int{"a": 1}, "a")
+	testCheck(c, check.HasKey, false, "", map[string]int{"a": 1}, "b")
+	testCheck(c, check.HasKey, true, "", map[interface{}]int{nil: 1}, nil)
+	testCheck(c, check.HasKey, false, "key must be of type string", map[string]int{"a": 1}, 1)
+
+	testCheck(c, check.HasKey, true, "", []int{1, 2}, 1)
+	testCheck(c, check.HasKey, false, "", []int{1, 2}, 2)
+	testCheck(c, check.HasKey, false, "", []int{1, 2}, -1)
+	testCheck(c, check.HasKey, true, "", "abc", 2)
+	testCheck(c, check.HasKey, false, "key must be an int index", []int{1, 2}, "1")
+
+	testCheck(c, check.HasKey, false, "obtained value type has no keys", 42, 42)
+}
+
+func (s *CheckersS) TestElementsMatch(c *check.C) {
+	testInfo(c, check.ElementsMatch, "ElementsMatch", []string{"obtained", "expected"})
+
+	testCheck(c, check.ElementsMatch, true, "", []int{1, 2, 3}, []int{3, 1, 2})
+	testCheck(c, check.ElementsMatch, true, "", []int{}, [0]int{})
+	testCheck(c, check.ElementsMatch, true, "", []int{1, 1, 2}, []int{1, 2, 1})
+	testCheck(c, check.ElementsMatch, false, "Difference:\n...     Missing: 1\n", []int{1, 2}, []int{1, 2, 1})
+	testCheck(c, check.ElementsMatch, false, "Difference:\n...     Extra: 3\n", []int{1, 2, 3}, []int{2, 1})
+	testCheck(c, check.ElementsMatch, false, ""+
+		"Difference:\n"+
+		"...     Missing: \"d\", \"c\"\n"+
+		"...     Extra: \"x\"\n",
+		[]string{"a", "x", "b"}, []string{"d", "b", "c", "a"})
+
+	// Some bad values
+	testCheck(c, check.ElementsMatch, false, "obtained value must be a slice or an array", "abc", []string{"a"})
+	testCheck(c, check.ElementsMatch, false, "expected value must be a slice or an array", []int{1}, nil)
+}
+
+func (s *CheckersS) TestIsSubsetOf(c *check.C) {
+	testInfo(c, check.IsSubsetOf, "IsSubsetOf", []string{"obtained", "superset"})
+
+	// Slices and arrays
+	testCheck(c, check.IsSubsetOf, true, "", []int{3, 1}, []int{1, 2, 3})
+	testCheck(c, check.IsSubsetOf, true, "", []int{}, [1]int{1})
+	testCheck(c, check.IsSubsetOf, false, "Not in superset: 4, 1", []int{4, 1, 1}, []int{1, 2, 3})
+
+	// Maps
+	testCheck(c, check.IsSubsetOf, true, "", map[string]int{"a": 1}, map[string]int{"a": 1, "b": 2})
+	testCheck(c, check.IsSubsetOf, false, ""+
+		"Difference:\n"+
+		"...     Key \"a\": 1 != 2\n"+
+		"...     Key \"c\" not in superset\n",
+		map[string]int{"c": 3, "a": 1, "b": 2}, map[string]int{"a": 2, "b": 2})
+
+	// Some bad values
+	testCheck(c, check.IsSubsetOf, false, "obtained and superset maps must have the same key type",
+		map[string]int{}, map[int]int{})
+	testCheck(c, check.IsSubsetOf, false, "obtained and superset must both be slices or arrays, or both be maps",
+		[]int{1}, map[int]int{1: 1})
+}
+
+func (s *CheckersS) TestGreaterThan(c *check.C) {
+	testInfo(c, check.GreaterThan, "GreaterThan", []string{"obtained", "expected"})
+
+	testCheck(c, check.GreaterThan, true, "", 2, 1)
+	testCheck(c, check.GreaterThan, false, "", 1, 1)
+	testCheck(c, check.GreaterThan, false, "", -1, 1)
+	testCheck(c, check.GreaterThan, true, "", uint8(255), uint8(0))
+	testCheck(c, check.GreaterThan, true, "", 1.5, 1.25)
+	testCheck(c, check.GreaterThan, true, "", "b", "abc")
+	testCheck(c, check.GreaterThan, true, "", time.Second, time.Millisecond)
+	testCheck(c, check.GreaterThan, true, "", time.Unix(2, 0), time.Unix(1, 0))
+	testCheck(c, check.GreaterThan, false, "", time.Unix(1, 0), time.Unix(1, 0))
+
+	// Some bad values
+	testCheck(c, check.GreaterThan, false, "expected must be of type int64, as obtained is", int64(2), 1)
+	testCheck(c, check.GreaterThan, false, "expected must be of type time.Duration, as obtained is", time.Second, int64(1))
+	testCheck(c, check.GreaterThan, false, "expected must be of type int, as obtained is", 1, nil)
+	testCheck(c, check.GreaterThan, false, "obtained value type is not ordered", []int{2}, []int{1})
+	testCheck(c, check.GreaterThan, false, "obtained value type is not ordered", nil, 1)
+	testCheck(c, check.GreaterThan, false, "NaN can't be ordered", math.NaN(), 1.0)
+}
+
+func (s *CheckersS) TestGreaterOrEqual(c *check.C) {
+	testInfo(c, check.GreaterOrEqual, "GreaterOrEqual", []string{"obtained", "expected"})
+
+	testCheck(c, check.GreaterOrEqual, true, "", 2, 1)
+	testCheck(c, check.GreaterOrEqual, true, "", 1, 1)
+	testCheck(c, check.GreaterOrEqual, false, "", 0, 1)
+	testCheck(c, check.GreaterOrEqual, true, "", "a", "a")
+	testCheck(c, check.GreaterOrEqual, false, "expected must be of type float64, as obtained is", 1.0, float32(1))
+}
+
+func (s *CheckersS) TestLessThan(c *check.C) {
+	testInfo(c, check.LessThan, "LessThan", []string{"obtained", "expected"})
+
+	testCheck(c, check.LessThan, true, "", 1, 2)
+	testCheck(c, check.LessThan, false, "", 1, 1)
+	testCheck(c, check.LessThan, true, "", uint(1), uint(2))
+	testCheck(c, check.LessThan, true, "", time.Unix(1, 0), time.Unix(1, 1))
+	testCheck(c, check.LessThan, false, "obtained value type is not ordered", true, false)
+}
+
+func (s *CheckersS) TestLessOrEqual(c *check.C) {
+	testInfo(c, check.LessOrEqual, "LessOrEqual", []string{"obtained", "expected"})
+
+	testCheck(c, check.LessOrEqual, true, "", 1, 2)
+	testCheck(c, check.LessOrEqual, true, "", 2, 2)
+	testCheck(c, check.LessOrEqual, false, "", 3, 2)
+	testCheck(c, check.LessOrEqual, true, "", -time.Second, time.Duration(0))
+}
+
+func (s *CheckersS) TestBetween(c *check.C) {
+	testInfo(c, check.Between, "Between", []string{"obtained", "min", "max"})
+
+	testCheck(c, check.Between, true, "", 2, 1, 3)
+	testCheck(c, check.Between, true, "", 1, 1, 3)
+	testCheck(c, check.Between, true, "", 3, 1, 3)
+	testCheck(c, check.Between, false, "", 0, 1, 3)
+	testCheck(c, check.Between, false, "", 4, 1, 3)
+	testCheck(c, check.Between, true, "", 0.5, 0.0, 1.0)
+	testCheck(c, check.Between, true, "", "b", "a", "c")
+	testCheck(c, check.Between, true, "", time.Unix(2, 0), time.Unix(1, 0), time.Unix(3, 0))
+
+	// Some bad values
+	testCheck(c, check.Between, false, "min is greater than max", 2, 3, 1)
+	testCheck(c, check.Between, false, "max must be of type int, as min is", 2, 1, int64(3))
+	testCheck(c, check.Between, false, "min must be of type int64, as obtained is", int64(2), 1, 3)
+}
+
+type approxPoint struct {
+	X, Y float64
+	Tag  string
+}
+
+type approxHidden struct {
+	m map[string]float64
+	t time.Time
+}
+
+type approxNode struct {
+	Value float64
+	Next  *approxNode
+}
+
+func (s *CheckersS) TestEqualsWithin(c *check.C) {
+	testInfo(c, check.EqualsWithin, "EqualsWithin", []string{"obtained", "expected", "tolerance"})
+
+	testCheck(c, check.EqualsWithin, true, "", 1.0, 1.05, 0.1)
+	testCheck(c, check.EqualsWithin, false, "1 != 1.5 (delta 0.5, tolerance 0.1)", 1.0, 1.5, 0.1)
+	testCheck(c, check.EqualsWithin, true, "", 10, 12, 2)
+	testCheck(c, check.EqualsWithin, true, "", float32(1), float32(1.01), 0.1)
+	testCheck(c, check.EqualsWithin, true, "", nil, nil, 0.1)
+	testCheck(c, check.EqualsWithin, true, "", time.Second, 1010*time.Millisecond, 20*time.Millisecond)
+	testCheck(c, check.EqualsWithin, false, "1s != 1.1s (delta 100ms, tolerance 20ms)",
+		time.Second, 1100*time.Millisecond, 20*time.Millisecond)
+
+	// NaN and infinities
+	testCheck(c, check.EqualsWithin, true, "", math.NaN(), math.NaN(), 0.1)
+	testCheck(c, check.EqualsWithin, false, "NaN != 1", math.NaN(), 1.0, 0.1)
+	testCheck(c, check.EqualsWithin, true, "", math.Inf(1), math.Inf(1), 0.1)
+	testCheck(c, check.EqualsWithin, false, "+Inf != -Inf", math.Inf(1), math.Inf(-1), math.Inf(1))
+	testCheck(c, check.EqualsWithin, false, "+Inf != 1", math.Inf(1), 1.0, math.Inf(1))
+	testCheck(c, check.EqualsWithin, true, "", 1.0, 1e300, math.Inf(1))
+
+	// Recursive comparisons
+	testCheck(c, check.EqualsWithin, true, "", []float64{1, 2}, []float64{1.01, 1.99}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [1]: 2 != 3 (delta 1, tolerance 0.1)", []float64{1, 2}, []float64{1, 3}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "Length 1 != 2", []float64{1}, []float64{1, 2}, 0.1)
+	testCheck(c, check.EqualsWithin, true, "", [2]float64{1, 2}, [2]float64{1.01, 1.99}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [\"b\"]: 2 != 2.5 (delta 0.5, tolerance 0.1)",
+		map[string]float64{"a": 1, "b": 2}, map[string]float64{"a": 1, "b": 2.5}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [\"b\"]: Missing from expected",
+		map[string]float64{"a": 1, "b": 2}, map[string]float64{"a": 1, "c": 2}, 0.1)
+	testCheck(c, check.EqualsWithin, true, "",
+		approxPoint{1, 2, "p"}, approxPoint{1.01, 2.01, "p"}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At .Y: 2 != 3 (delta 1, tolerance 0.1)",
+		approxPoint{1, 2, "p"}, approxPoint{1, 3, "p"}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At .Tag: \"p\" != \"q\"",
+		approxPoint{1, 2, "p"}, approxPoint{1, 2, "q"}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [0].X: 1 != NaN",
+		[]*approxPoint{{1, 2, ""}}, []*approxPoint{{math.NaN(), 2, ""}}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [1]: Only one of the values is nil",
+		[]*approxPoint{{}, {}}, []*approxPoint{{}, nil}, 0.1)
+	testCheck(c, check.EqualsWithin, true, "",
+		[]interface{}{1.0, "a"}, []interface{}{1.01, "a"}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [0]: Type float64 != int",
+		[]interface{}{1.0}, []interface{}{1}, 0.1)
+
+	// Unexported fields
+	testCheck(c, check.EqualsWithin, false, "At .m[\"a\"]: 1 != 2 (delta 1, tolerance 0.1)",
+		approxHidden{m: map[string]float64{"a": 1}}, approxHidden{m: map[string]float64{"a": 2}}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At .t: Cannot compare unexported time.Time",
+		approxHidden{t: time.Unix(1, 0)}, approxHidden{t: time.Unix(1, 0)}, 0.1)
+
+	// Times
+	now := time.Now()
+	testCheck(c, check.EqualsWithin, true, "", now, now.UTC(), 0.1)
+	testCheck(c, check.EqualsWithin, true, "", []time.Time{time.Unix(1, 5)}, []time.Time{time.Unix(1, 5).UTC()}, 0.1)
+	testCheck(c, check.EqualsWithin, false, "At [0]: "+time.Unix(1, 0).String()+" != "+time.Unix(1, 50).String(),
+		[]time.Time{time.Unix(1, 0)}, []time.Time{time.Unix(1, 50)}, 100)
+
+	// Cyclic values
+	cycleA, cycleB := &approxNode{Value: 1}, &approxNode{Value: 1.5}
+	cycleA.Next, cycleB.Next = cycleA, cycleB
+	testCheck(c, check.EqualsWithin, true, "", cycleA, cycleB, 1)
+	testCheck(c, check.EqualsWithin, false, "At .Value: 1 != 1.5 (delta 0.5, tolerance 0.1)", cycleA, cycleB, 0.1)
+	sliceA, sliceB := []interface{}{1.0, nil}, []interface{}{1.5, nil}
+	sliceA[1], sliceB[1] = sliceA, sliceB
+	testCheck(c, check.EqualsWithin, true, "", sliceA, sliceB, 1)
+	testCheck(c, check.EqualsWithin, false, "At [0]: 1 != 1.5 (delta 0.5, tolerance 0.1)", sliceA, sliceB, 0.1)
+	mapA, mapB := map[string]interface{}{"v": 1.0}, map[string]interface{}{"v": 1.5}
+	mapA["self"], mapB["self"] = mapA, mapB
+	testCheck(c, check.EqualsWithin, true, "", mapA, mapB, 1)
+	testCheck(c, check.EqualsWithin, false, "At [\"v\"]: 1 != 1.5 (delta 0.5, tolerance 0.1)", mapA, mapB, 0.1)
+
+	// Large integers are compared exactly
+	testCheck(c, check.EqualsWithin, false, "1152921504606846976 != 1152921504606846977 (delta 1, tolerance 0)",
+		int64(1<<60), int64(1<<60+1), 0)
+	testCheck(c, check.EqualsWithin, true, "", int64(math.MinInt64), int64(math.MaxInt64), math.Inf(1))
+	testCheck(c, check.EqualsWithin, false, "0 != 18446744073709551615 (delta 18446744073709551615, tolerance 1e+19)",
+		uint64(0), uint64(math.MaxUint64), 1e19)
+	testCheck(c, check.EqualsRelative, false, "1152921504606846976 != 1152921504606846977 (relative delta 8.67e-19, epsilon 0)",
+		int64(1<<60), int64(1<<60+1), 0)
+
+	// Some bad values
+	testCheck(c, check.EqualsWithin, false, "expected must be of type float64, as obtained is", 1.0, 1, 0.1)
+	testCheck(c, check.EqualsWithin, false, "tolerance must be a non-negative number", 1.0, 1.0, -0.1)
+	testCheck(c, check.EqualsWithin, false, "tolerance must be a non-negative number", 1.0, 1.0, "0.1")
+}
+
+func (s *CheckersS) TestEqualsRelative(c *check.C) {
+	testInfo(c, check.EqualsRelative, "EqualsRelative", []string{"obtained", "expected", "epsilon"})
+
+	testCheck(c, check.EqualsRelative, true, "", 1000.0, 1001.0, 0.01)
+	testCheck(c, check.EqualsRelative, false, "1000 != 1100 (relative delta 0.0909, epsilon 0.01)", 1000.0, 1100.0, 0.01)
+	testCheck(c, check.EqualsRelative, true, "", 0.0, 0.0, 0.01)
+	testCheck(c, check.EqualsRelative, false, "0 != 1e-10 (relative delta 1, epsilon 0.01)", 0.0, 1e-10, 0.01)
+	testCheck(c, check.EqualsRelative, true, "", math.NaN(), math.NaN(), 0.01)
+	testCheck(c, check.EqualsRelative, true, "", math.Inf(-1), math.Inf(-1), 0.01)
+	testCheck(c, check.EqualsRelative, true, "", []float64{1e6, 1e-6}, []float64{1.001e6, 1.001e-6}, 0.01)
+	testCheck(c, check.EqualsRelative, false, "At [1]: 1e-06 != 2e-06 (relative delta 0.5, epsilon 0.01)",
+		[]float64{1e6, 1e-6}, []float64{1e6, 2e-6}, 0.01)
+}
+
+func (s *CheckersS) TestTimeWithin(c *check.C) {
+	testInfo(c, check.TimeWithin, "TimeWithin", []string{"obtained", "expected", "delta"})
+
+	t := time.Unix(1000, 0)
+	testCheck(c, check.TimeWithin, true, "", t, t, time.Duration(0))
+	testCheck(c, check.TimeWithin, true, "", t.Add(time.Second), t, time.Second)
+	testCheck(c, check.TimeWithin, true, "", t.Add(-time.Second), t, time.Second)
+	testCheck(c, check.TimeWithin, false, "Difference of 1.5s is more than 1s", t.Add(-1500*time.Millisecond), t, time.Second)
+	testCheck(c, check.TimeWithin, true, "", t.UTC(), t.In(time.FixedZone("X", 3600)), time.Duration(0))
+
+	// Times too far apart for their difference to fit in a time.Duration
+	year := func(y int) time.Time { return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC) }
+	testCheck(c, check.TimeWithin, false, "Difference of over 2562047h47m16.854775807s is more than 1m0s",
+		time.Time{}, time.Now(), time.Minute)
+	testCheck(c, check.TimeWithin, false, "Difference of over 2562047h47m16.854775807s is more than 1m0s",
+		time.Now(), time.Time{}, time.Minute)
+	testCheck(c, check.TimeWithin, false, "Difference of over 2562047h47m16.854775807s is more than 2562047h47m16.854775807s",
+		year(1000), year(3000), time.Duration(math.MaxInt64))
+	testCheck(c, check.TimeWithin, false, "Difference of over 2562047h47m16.854775807s is more than 2562047h47m16.854775807s",
+		year(3000), year(1000), time.Duration(math.MaxInt64))
+
+	// Some bad values
+	testCheck(c, check.TimeWithin, false, "obtained value must be a time.Time", 1, t, time.Second)
+	testCheck(c, check.TimeWithin, false, "expected value must be a time.Time", t, 1, time.Second)
+	testCheck(c, check.TimeWithin, false, "delta must be a non-negative time.Duration", t, t, 1)
+	testCheck(c, check.TimeWithin, false, "delta must be a non-negative time.Duration", t, t, -time.Second)
+}
+
+type deepOwner struct {
+	ID   int
+	Name string
+}
+
+type deepRecord struct {
+	ID      int
+	Owner   *deepOwner
+	Tags    []string
+	Attrs   map[string]int
+	Created time.Time
+	secret  string
+}
+
+func (s *CheckersS) TestDeepEqualsWith(c *check.C) {
+	testInfo(c, check.DeepEqualsWith(), "DeepEqualsWith", []string{"obtained", "expected"})
+
+	a := deepRecord{1, &deepOwner{1, "bob"}, []string{"a", "b"}, map[string]int{"x": 1}, time.Unix(1, 0), "s"}
+	b := deepRecord{1, &deepOwner{1, "bob"}, []string{"a", "b"}, map[string]int{"x": 1}, time.Unix(1, 0), "s"}
+	testCheck(c, check.DeepEqualsWith(), true, "", a, b)
+	testCheck(c, check.DeepEqualsWith(), true, "", nil, nil)
+	testCheck(c, check.DeepEqualsWith(), true, "", []int{1, 2}, []int{1, 2})
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     [1]: 2 != 3\n", []int{1, 2}, []int{1, 3})
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     int != int64\n", 1, int64(1))
+
+	b.ID, b.Owner.ID, b.Tags[1], b.Attrs["x"], b.secret = 2, 2, "c", 2, "t"
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n"+
+		"...     ID: 1 != 2\n"+
+		"...     Owner.ID: 1 != 2\n"+
+		"...     Tags[1]: \"b\" != \"c\"\n"+
+		"...     Attrs[\"x\"]: 1 != 2\n"+
+		"...     secret: \"s\" != \"t\"\n", a, b)
+
+	// Ignored fields, by name or by path
+	testCheck(c, check.DeepEqualsWith(check.IgnoreFields("ID", "Tags", "Attrs"), check.IgnoreUnexported()), true, "", a, b)
+	testCheck(c, check.DeepEqualsWith(check.IgnoreFields("Owner.ID", "Tags", "Attrs", "secret")), false,
+		"Difference:\n...     ID: 1 != 2\n", a, b)
+	testCheck(c, check.DeepEqualsWith(check.IgnoreFields("ID")), true, "",
+		[]deepOwner{{1, "bob"}}, []deepOwner{{2, "bob"}})
+
+	// Unexported fields of types without exported ones are still compared
+	ignoreUnexported := check.DeepEqualsWith(check.IgnoreUnexported())
+	testCheck(c, ignoreUnexported, true, "", time.Unix(1, 0), time.Unix(1, 0).UTC())
+	testCheck(c, ignoreUnexported, false, fmt.Sprintf("Difference:\n...     %#v != %#v\n", time.Unix(1, 0), time.Unix(2, 0)),
+		time.Unix(1, 0), time.Unix(2, 0))
+	testCheck(c, ignoreUnexported, false, fmt.Sprintf("Difference:\n...     Created: %#v != %#v\n", time.Unix(1, 0), time.Unix(2, 0)),
+		deepRecord{Created: time.Unix(1, 0)}, deepRecord{Created: time.Unix(2, 0)})
+	errA, errB := errors.New("a"), errors.New("b")
+	testCheck(c, ignoreUnexported, true, "", errA, errA)
+	testCheck(c, ignoreUnexported, false, fmt.Sprintf("Difference:\n...     %#v != %#v\n",
+		reflect.ValueOf(errA).Elem(), reflect.ValueOf(errB).Elem()), errA, errB)
+
+	// Nil and empty values
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     []int(nil) != []int{}\n", []int(nil), []int{})
+	testCheck(c, check.DeepEqualsWith(check.EquateEmpty()), true, "", []int(nil), []int{})
+	testCheck(c, check.DeepEqualsWith(check.EquateEmpty()), true, "", map[string]int{}, map[string]int(nil))
+	testCheck(c, check.DeepEqualsWith(check.EquateEmpty()), false,
+		"Difference:\n...     []int[0] != []int[1]\n", []int(nil), []int{1})
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n"+
+		"...     [\"a\"]: 1 != (missing)\n"+
+		"...     [\"b\"]: (missing) != 2\n", map[string]int{"a": 1}, map[string]int{"b": 2})
+
+	// Sorted slices
+	byID := check.SortSlices(func(a, b deepOwner) bool { return a.ID < b.ID })
+	testCheck(c, check.DeepEqualsWith(byID), true, "",
+		[]deepOwner{{2, "b"}, {1, "a"}}, []deepOwner{{1, "a"}, {2, "b"}})
+	testCheck(c, check.DeepEqualsWith(byID), false, "Difference:\n...     [1].Name: \"b\" != \"c\"\n",
+		[]deepOwner{{2, "b"}, {1, "a"}}, []deepOwner{{1, "a"}, {2, "c"}})
+	testCheck(c, check.DeepEqualsWith(byID), false, "Difference:\n...     [0]: 2 != 1\n...     [1]: 1 != 2\n",
+		[]int{2, 1}, []int{1, 2})
+
+	// Custom comparers
+	sameSecond := check.Comparer(func(a, b time.Time) bool { return a.Unix() == b.Unix() })
+	testCheck(c, check.DeepEqualsWith(sameSecond), true, "",
+		deepRecord{Created: time.Unix(1, 0)}, deepRecord{Created: time.Unix(1, 5).UTC()})
+	caseless := check.Comparer(func(a, b string) bool { return strings.EqualFold(a, b) })
+	testCheck(c, check.DeepEqualsWith(caseless), true, "", []string{"a", "B"}, []string{"A", "b"})
+	testCheck(c, check.DeepEqualsWith(caseless), false, "Difference:\n...     Owner.Name: \"Bob\" != \"alice\"\n",
+		deepRecord{Owner: &deepOwner{1, "Bob"}}, deepRecord{Owner: &deepOwner{1, "alice"}})
+
+	// Cyclic values
+	cycleA, cycleB := &cyclicNode{}, &cyclicNode{}
+	cycleA.Next, cycleB.Next = cycleA, cycleB
+	testCheck(c, check.DeepEqualsWith(), true, "", cycleA, cycleB)
+	sliceA, sliceB := []interface{}{1, nil}, []interface{}{2, nil}
+	sliceA[1], sliceB[1] = sliceA, sliceB
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     [0]: 1 != 2\n", sliceA, sliceB)
+	sliceB[0] = 1
+	testCheck(c, check.DeepEqualsWith(), true, "", sliceA, sliceB)
+	mapA, mapB := map[string]interface{}{"v": 1}, map[string]interface{}{"v": 2}
+	mapA["self"], mapB["self"] = mapA, mapB
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     [\"v\"]: 1 != 2\n", mapA, mapB)
+	mapB["v"] = 1
+	testCheck(c, check.DeepEqualsWith(), true, "", mapA, mapB)
+
+	// Functions
+	f := func() {}
+	testCheck(c, check.DeepEqualsWith(), true, "", (func())(nil), (func())(nil))
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     Cannot compare non-nil func() without a Comparer\n", f, f)
+	testCheck(c, check.DeepEqualsWith(), false, "Difference:\n...     [0]: Cannot compare non-nil func() without a Comparer\n",
+		[]func(){nil}, []func(){f})
+	sameFunc := check.Comparer(func(a, b func()) bool { return (a == nil) == (b == nil) })
+	testCheck(c, check.DeepEqualsWith(sameFunc), true, "", f, f)
+
+	// Some bad options
+	testCheck(c, check.DeepEqualsWith(check.SortSlices(1)), false, "SortSlices needs a func(T, T) bool, got int", 1, 1)
+	testCheck(c, check.DeepEqualsWith(check.Comparer(func(a int, b string) bool { return false })), false,
+		"Comparer needs a func(T, T) bool, got func(int, string) bool", 1, 1)
+}
+
+type cyclicNode struct {
+	Next *cyclicNode
+}
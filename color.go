@@ -0,0 +1,94 @@
+package check
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// -----------------------------------------------------------------------
+// Colors of the output on terminals.
+
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// Tell whether output should be colored given the RunConf.Color value,
+// which is "auto" (the default), "always" or "never".
+func parseColor(value string, output io.Writer) (bool, error) {
+	switch value {
+	case "", "auto":
+		return isTerminal(output), nil
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	}
+	return false, errors.New("Bad color value: " + value)
+}
+
+// Tell whether output goes to a terminal able to show colors.
+func isTerminal(output io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func colorize(color, s string) string {
+	return color + s + colorReset
+}
+
+func labelColor(label string) string {
+	switch label {
+	case "PASS":
+		return colorGreen
+	case "FAIL", "PANIC", "TIMEOUT":
+		return colorRed
+	case "SKIP", "MISS", "FAIL EXPECTED", "FLAKY", "SLOW":
+		return colorYellow
+	}
+	return ""
+}
+
+// Color the obtained and expected values logged by failed checks, and
+// both sides of the differences between them.
+func colorizeLog(log string) string {
+	lines := strings.SplitAfter(log, "\n")
+	inDiff := false
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "... obtained "):
+			lines[i] = "... " + colorize(colorRed, "obtained") + line[len("... obtained"):]
+		case strings.HasPrefix(line, "... expected "):
+			lines[i] = "... " + colorize(colorGreen, "expected") + line[len("... expected"):]
+		case line == "... Difference:\n" || line == "... String difference:\n":
+			inDiff = true
+			continue
+		case inDiff && strings.HasPrefix(line, "...     "):
+			if j := strings.Index(line, " != "); j >= 0 {
+				// As in "...     Field[1]: obtained != expected".
+				start := len("...     ")
+				if k := strings.Index(line[start:j], ": "); k >= 0 && !strings.Contains(line[start:start+k], " ") {
+					start += k + len(": ")
+				}
+				end := strings.TrimRight(line, "\n")
+				line = line[:start] + colorize(colorRed, line[start:j]) +
+					" != " + colorize(colorGreen, end[j+len(" != "):]) + line[len(end):]
+			}
+			lines[i] = line
+			continue
+		}
+		inDiff = false
+	}
+	return strings.Join(lines, "")
+}
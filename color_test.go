@@ -0,0 +1,61 @@
+package check_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&colorS{})
+
+type colorS struct{}
+
+type ColorHelper struct{}
+
+func (s *ColorHelper) TestDiff(c *C) {
+	c.Check([]int{1, 2}, DeepEquals, []int{1, 3})
+}
+
+// Run the color test along with passing and skipped ones, returning the
+// output.
+func runColor(runConf *RunConf) string {
+	runConf.Filter = "TestDiff|TestPass|TestSkip"
+	_, output := runCapturing(runConf, &ColorHelper{}, &OutcomeHelper{})
+	return output
+}
+
+func (s *colorS) TestColorAlways(c *C) {
+	output := runColor(&RunConf{Verbose: true, Color: "always"})
+	c.Check(output, Matches, "(?s)\n-+\n"+
+		"\x1b\\[31mFAIL\x1b\\[0m: color_test\\.go:[0-9]+: ColorHelper\\.TestDiff\n\n"+
+		"color_test\\.go:[0-9]+:\n"+
+		"    c\\.Check\\(.*\\)\n"+
+		"\\.\\.\\. \x1b\\[31mobtained\x1b\\[0m \\[\\]int = \\[\\]int{1, 2}\n"+
+		"\\.\\.\\. \x1b\\[32mexpected\x1b\\[0m \\[\\]int = \\[\\]int{1, 3}\n"+
+		"\\.\\.\\. Difference:\n"+
+		"\\.\\.\\.     \\[1\\]: \x1b\\[31m2\x1b\\[0m != \x1b\\[32m3\x1b\\[0m\n\n\n"+
+		"\x1b\\[32mPASS\x1b\\[0m: check_test\\.go:[0-9]+: OutcomeHelper\\.TestPass\t *[.0-9]+s\n"+
+		"\x1b\\[33mSKIP\x1b\\[0m: check_test\\.go:[0-9]+: OutcomeHelper\\.TestSkip \\(Not now\\)\n")
+}
+
+func (s *colorS) TestColorNever(c *C) {
+	output := runColor(&RunConf{Verbose: true, Color: "never"})
+	c.Check(strings.Contains(output, "\x1b"), Equals, false)
+}
+
+func (s *colorS) TestColorAutoWithoutTerminal(c *C) {
+	output := runColor(&RunConf{Verbose: true})
+	c.Check(output, Matches, "(?s).*FAIL: color_test\\.go.*")
+	c.Check(strings.Contains(output, "\x1b"), Equals, false)
+}
+
+func (s *colorS) TestColorWithSuiteParallelism(c *C) {
+	_, output := runCapturing(&RunConf{Color: "always", SuiteParallelism: 2}, &ColorHelper{}, &SuccessHelper{})
+	c.Check(output, Matches, "(?s).*\x1b\\[31mFAIL\x1b\\[0m: color_test\\.go.*")
+}
+
+func (s *colorS) TestBadColor(c *C) {
+	output := String{}
+	result := Run(&ColorHelper{}, &RunConf{Output: &output, Color: "sometimes"})
+	c.Check(result.String(), Equals, "ERROR: Bad color value: sometimes")
+}
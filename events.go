@@ -0,0 +1,164 @@
+package check
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+// Stream of JSON events in the format of go tool test2json.
+
+type eventWriter struct {
+	m       sync.Mutex
+	writer  io.Writer
+	pkg     string
+	started time.Time
+}
+
+// Field names match the TestEvent type documented by test2json.
+type event struct {
+	Time    time.Time
+	Action  string
+	Package string   `json:",omitempty"`
+	Test    string   `json:",omitempty"`
+	Elapsed *float64 `json:",omitempty"`
+	Output  string   `json:",omitempty"`
+}
+
+func newEventWriter(writer io.Writer, suites []interface{}) *eventWriter {
+	return &eventWriter{writer: writer, pkg: suitesPackage(suites)}
+}
+
+// Return the import path of the package defining the suites, as go test
+// reports it, so without the _test suffix of external test packages.
+// Suites are normally all defined by the package under test, so the
+// first one is used.
+func suitesPackage(suites []interface{}) string {
+	for _, suite := range suites {
+		t := reflect.TypeOf(suite)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil {
+			continue
+		}
+		if pkg := t.PkgPath(); pkg != "" {
+			return strings.TrimSuffix(pkg, "_test")
+		}
+	}
+	return ""
+}
+
+func (ew *eventWriter) emit(e *event) {
+	e.Time = time.Now()
+	e.Package = ew.pkg
+	data, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	ew.m.Lock()
+	ew.writer.Write(append(data, '\n'))
+	ew.m.Unlock()
+}
+
+// Emit one output event per line of the text.
+func (ew *eventWriter) output(test, text string) {
+	for text != "" {
+		line := text
+		if i := strings.Index(text, "\n"); i >= 0 {
+			line = text[:i+1]
+		}
+		text = text[len(line):]
+		ew.emit(&event{Action: "output", Test: test, Output: line})
+	}
+}
+
+func (ew *eventWriter) RunStarted() {
+	ew.started = time.Now()
+	ew.emit(&event{Action: "start"})
+}
+
+func (ew *eventWriter) SuiteStarted(suite string)                  {}
+func (ew *eventWriter) SuiteFinished(suite string, result *Result) {}
+
+func (ew *eventWriter) CallStarted(call *CallInfo) {
+	if !call.Fixture {
+		ew.emit(&event{Action: "run", Test: call.Test})
+	}
+}
+
+func (ew *eventWriter) callPaused(call *CallInfo, paused bool) {
+	action := "cont"
+	if paused {
+		action = "pause"
+	}
+	ew.emit(&event{Action: action, Test: call.Test})
+}
+
+// Emit the events for a finished call, with the output that outputWriter
+// would have reported for it in verbose mode. Fixtures only get reported
+// when they fail or panic.
+func (ew *eventWriter) CallFinished(result *CallResult) {
+	var label, action string
+	switch result.Status {
+	case CallSucceeded:
+		label, action = "PASS", "pass"
+		if result.ExpectedFailure {
+			label = "FAIL EXPECTED"
+		} else if result.Retries > 0 {
+			label = "FLAKY"
+		} else if result.Slow {
+			label = "SLOW"
+		} else if strings.HasPrefix(result.Method, "Benchmark") {
+			action = "bench"
+		}
+	case CallSkipped:
+		label, action = "SKIP", "skip"
+	case CallMissed:
+		label, action = "MISS", "skip"
+	case CallFailed:
+		label, action = "FAIL", "fail"
+	case CallPanicked, CallFixturePanicked:
+		label, action = "PANIC", "fail"
+	case CallTimedOut:
+		label, action = "TIMEOUT", "fail"
+	default:
+		return
+	}
+	test := result.Test
+	if result.Fixture {
+		if action != "fail" {
+			return
+		}
+		test = result.Suite + "." + result.Method
+		ew.emit(&event{Action: "run", Test: test})
+	}
+	var suffix string
+	if result.Reason != "" && (action == "skip" || result.ExpectedFailure) {
+		suffix = " (" + result.Reason + ")"
+	}
+	if action == "pass" || action == "bench" {
+		suffix += "\t" + result.Timing
+	}
+	ew.output(test, renderCallHeader(label, &result.CallInfo, "", suffix+"\n"))
+	if action == "fail" || label == "FLAKY" || label == "SLOW" {
+		ew.output(test, result.Log)
+	}
+	elapsed := result.Duration.Seconds()
+	ew.emit(&event{Action: action, Test: test, Elapsed: &elapsed})
+}
+
+// Emit the final event of the run.
+func (ew *eventWriter) RunFinished(result *Result) {
+	action := "pass"
+	if !result.Passed() {
+		action = "fail"
+	}
+	ew.output("", result.String()+"\n")
+	elapsed := time.Since(ew.started).Seconds()
+	ew.emit(&event{Action: action, Elapsed: &elapsed})
+}
@@ -0,0 +1,110 @@
+package check_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&eventsS{})
+
+type eventsS struct{}
+
+type EventsHelper struct{}
+
+func (s *EventsHelper) TestParallel(c *C) {
+	c.Parallel()
+}
+
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed *float64
+	Output  string
+}
+
+func decodeEvents(c *C, data []byte) []testEvent {
+	var events []testEvent
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		var e testEvent
+		c.Assert(json.Unmarshal([]byte(line), &e), IsNil, Commentf("Line: %q", line))
+		c.Assert(e.Time.IsZero(), Equals, false)
+		c.Assert(e.Package, Equals, "gopkg.in/check.v1")
+		events = append(events, e)
+	}
+	return events
+}
+
+func (s *eventsS) TestEvents(c *C) {
+	var buf bytes.Buffer
+	result, _ := runCapturing(&RunConf{EventWriter: &buf}, &EventsHelper{}, &OutcomeHelper{})
+	c.Assert(result.Failed, Equals, 1)
+
+	var actions []string
+	outputs := make(map[string]string)
+	for _, e := range decodeEvents(c, buf.Bytes()) {
+		switch e.Action {
+		case "output":
+			outputs[e.Test] += e.Output
+			continue
+		case "pass", "fail", "skip":
+			c.Check(e.Elapsed, NotNil)
+		}
+		actions = append(actions, e.Action+" "+e.Test)
+	}
+	c.Check(actions, DeepEquals, []string{
+		"start ",
+		"run EventsHelper.TestParallel",
+		"pause EventsHelper.TestParallel",
+		"cont EventsHelper.TestParallel",
+		"pass EventsHelper.TestParallel",
+		"run OutcomeHelper.TestExpectFailure",
+		"pass OutcomeHelper.TestExpectFailure",
+		"run OutcomeHelper.TestFail",
+		"fail OutcomeHelper.TestFail",
+		"run OutcomeHelper.TestPanic",
+		"fail OutcomeHelper.TestPanic",
+		"run OutcomeHelper.TestPass",
+		"pass OutcomeHelper.TestPass",
+		"run OutcomeHelper.TestSkip",
+		"skip OutcomeHelper.TestSkip",
+		"fail ",
+	})
+	c.Check(outputs["OutcomeHelper.TestFail"], Matches,
+		"(?s)FAIL: check_test\\.go:[0-9]+: OutcomeHelper\\.TestFail\nFailing\ncheck_test\\.go:[0-9]+:\n.*")
+	c.Check(outputs["OutcomeHelper.TestPass"], Matches,
+		"PASS: check_test\\.go:[0-9]+: OutcomeHelper\\.TestPass\t *[.0-9]+s\n")
+	c.Check(outputs["OutcomeHelper.TestSkip"], Matches,
+		"SKIP: check_test\\.go:[0-9]+: OutcomeHelper\\.TestSkip \\(Not now\\)\n")
+	c.Check(outputs[""], Equals, "OOPS: 2 passed, 1 skipped, 1 expected failures, 1 FAILED, 1 PANICKED\n")
+}
+
+func (s *eventsS) TestEventsForFixtures(c *C) {
+	var buf bytes.Buffer
+	runCapturing(&RunConf{EventWriter: &buf}, &FixtureHelper{panicOn: "SetUpSuite"})
+
+	var actions []string
+	for _, e := range decodeEvents(c, buf.Bytes()) {
+		if e.Action != "output" {
+			actions = append(actions, e.Action+" "+e.Test)
+		}
+	}
+	c.Check(actions, DeepEquals, []string{
+		"start ",
+		"run FixtureHelper.SetUpSuite",
+		"fail FixtureHelper.SetUpSuite",
+		"run FixtureHelper.Test1",
+		"skip FixtureHelper.Test1",
+		"run FixtureHelper.Test2",
+		"skip FixtureHelper.Test2",
+		"fail ",
+	})
+}
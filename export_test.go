@@ -1,6 +1,10 @@
 package check
 
-import "io"
+import (
+	"io"
+	"testing"
+	"time"
+)
 
 func PrintLine(filename string, line int) (string, error) {
 	return printLine(filename, line)
@@ -14,6 +18,27 @@ func NewOutputWriter(writer io.Writer, stream, verbose bool) *outputWriter {
 	return newOutputWriter(writer, stream, verbose)
 }
 
+func RunSuites(suites []interface{}, runConf *RunConf) *Result {
+	return runSuites(suites, runConf)
+}
+
+func RunSuitesT(t *testing.T, suites []interface{}, runConf *RunConf) *Result {
+	t.Helper()
+	return runSuitesT(t, suites, runConf)
+}
+
 func (c *C) FakeSkip(reason string) {
 	c.reason = reason
 }
+
+func (c *C) CallInfo() *CallInfo {
+	return c.callInfo()
+}
+
+func (c *C) CallResult() *CallResult {
+	return c.callResult()
+}
+
+func SetClock(runConf *RunConf, now func() time.Time) {
+	runConf.now = now
+}
@@ -3,6 +3,9 @@
 package check_test
 
 import (
+	"context"
+	"time"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -482,3 +485,202 @@ func (s *FixtureS) TestSkipTest(c *C) {
 	c.Assert(len(helper.calls), Equals, 6)
 	c.Assert(result.Skipped, Equals, 1)
 }
+
+// -----------------------------------------------------------------------
+// Verify the lifetime of the contexts handed to tests and fixtures.
+
+type ContextHelper struct {
+	suiteCtx      context.Context
+	testCtx       context.Context
+	release       chan bool
+	errors        []string
+	stoppedByTest bool
+}
+
+func (s *ContextHelper) expect(ok bool, issue string) {
+	if !ok {
+		s.errors = append(s.errors, issue)
+	}
+}
+
+func (s *ContextHelper) SetUpSuite(c *C) {
+	s.suiteCtx = c.Context()
+	s.expect(s.suiteCtx.Err() == nil, "suite context cancelled in SetUpSuite")
+}
+
+func (s *ContextHelper) TearDownSuite(c *C) {
+	s.expect(c.Context() == s.suiteCtx, "different context in TearDownSuite")
+	s.expect(s.suiteCtx.Err() != nil, "suite context not cancelled in TearDownSuite")
+}
+
+func (s *ContextHelper) SetUpTest(c *C) {
+	s.testCtx = c.Context()
+	s.expect(s.testCtx != s.suiteCtx, "suite context used in SetUpTest")
+	s.expect(s.testCtx.Err() == nil, "test context cancelled in SetUpTest")
+}
+
+func (s *ContextHelper) TearDownTest(c *C) {
+	s.expect(c.Context() == s.testCtx, "different context in TearDownTest")
+	s.expect(s.testCtx.Err() != nil, "test context not cancelled in TearDownTest")
+	s.expect(s.suiteCtx.Err() == nil, "suite context cancelled in TearDownTest")
+}
+
+func (s *ContextHelper) Test1(c *C) {
+	s.expect(c.Context() == s.testCtx, "different context in test")
+	s.expect(s.testCtx.Err() == nil, "test context cancelled in test")
+}
+
+func (s *ContextHelper) Test2(c *C) {
+	c.FailNow()
+}
+
+func (s *ContextHelper) Test3(c *C) {
+	c.SetTimeout(50 * time.Millisecond)
+	<-c.Context().Done()
+	s.stoppedByTest = true
+	close(s.release)
+}
+
+func (s *FixtureS) TestContext(c *C) {
+	helper := ContextHelper{release: make(chan bool)}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output})
+	c.Check(helper.errors, IsNil)
+	c.Check(result.Succeeded, Equals, 1)
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.TimedOut, Equals, 1)
+
+	select {
+	case <-helper.release:
+	case <-time.After(5 * time.Second):
+		c.Fatal("test context not cancelled after timeout")
+	}
+	c.Check(helper.stoppedByTest, Equals, true)
+}
+
+// -----------------------------------------------------------------------
+// Verify that cleanups run in reverse order at the end of their scope.
+
+type CleanupHelper struct {
+	calls   []string
+	panicOn string
+}
+
+func (s *CleanupHelper) trace(name string) {
+	s.calls = append(s.calls, name)
+	if name == s.panicOn {
+		panic(name)
+	}
+}
+
+func (s *CleanupHelper) cleanup(c *C, name string) {
+	c.Cleanup(func() { s.trace(name) })
+}
+
+func (s *CleanupHelper) SetUpSuite(c *C) {
+	s.trace("SetUpSuite")
+	s.cleanup(c, "SetUpSuite cleanup 1")
+	s.cleanup(c, "SetUpSuite cleanup 2")
+}
+
+func (s *CleanupHelper) TearDownSuite(c *C) {
+	s.trace("TearDownSuite")
+}
+
+func (s *CleanupHelper) SetUpTest(c *C) {
+	s.trace("SetUpTest")
+	s.cleanup(c, "SetUpTest cleanup")
+}
+
+func (s *CleanupHelper) TearDownTest(c *C) {
+	s.trace("TearDownTest")
+}
+
+func (s *CleanupHelper) Test1(c *C) {
+	s.trace("Test1")
+	s.cleanup(c, "Test1 cleanup 1")
+	s.cleanup(c, "Test1 cleanup 2")
+	c.FailNow()
+}
+
+func (s *CleanupHelper) Test2(c *C) {
+	s.cleanup(c, "Test2 cleanup")
+	s.trace("Test2")
+}
+
+func (s *FixtureS) TestCleanupOrder(c *C) {
+	helper := CleanupHelper{panicOn: "Test2"}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output})
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Panicked, Equals, 1)
+	c.Check(helper.calls, DeepEquals, []string{
+		"SetUpSuite",
+		"SetUpTest", "Test1", "TearDownTest",
+		"Test1 cleanup 2", "Test1 cleanup 1", "SetUpTest cleanup",
+		"SetUpTest", "Test2", "TearDownTest",
+		"Test2 cleanup", "SetUpTest cleanup",
+		"TearDownSuite",
+		"SetUpSuite cleanup 2", "SetUpSuite cleanup 1",
+	})
+}
+
+func (s *FixtureS) TestPanicOnTestCleanup(c *C) {
+	helper := CleanupHelper{panicOn: "Test1 cleanup 2"}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output})
+	c.Check(result.Succeeded, Equals, 1)
+	c.Check(result.Panicked, Equals, 1)
+	c.Check(helper.calls[4:7], DeepEquals, []string{
+		"Test1 cleanup 2", "Test1 cleanup 1", "SetUpTest cleanup"})
+
+	expected := "^\n-+\n" +
+		"PANIC: fixture_test\\.go:[0-9]+: CleanupHelper\\.Test1\n\n" +
+		"\\.\\.\\. Panic: Test1 cleanup 2 \\(PC=[xA-F0-9]+\\)\n\n" +
+		"(.|\n)*" +
+		".*fixture_test.go:[0-9]+\n" +
+		"  in CleanupHelper.trace\n" +
+		"(.|\n)*$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *FixtureS) TestPanicOnSetUpTestCleanup(c *C) {
+	helper := CleanupHelper{panicOn: "SetUpTest cleanup"}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output})
+	c.Check(result.Succeeded, Equals, 0)
+	c.Check(result.Panicked, Equals, 0)
+	c.Check(result.FixturePanicked, Equals, 1)
+	c.Check(result.Missed, Equals, 2)
+	c.Check(helper.calls, DeepEquals, []string{
+		"SetUpSuite",
+		"SetUpTest", "Test1", "TearDownTest",
+		"Test1 cleanup 2", "Test1 cleanup 1", "SetUpTest cleanup",
+		"TearDownSuite",
+		"SetUpSuite cleanup 2", "SetUpSuite cleanup 1",
+	})
+
+	expected := "^\n-+\n" +
+		"PANIC: fixture_test\\.go:[0-9]+: CleanupHelper\\.SetUpTest\n\n" +
+		"\\.\\.\\. Panic: SetUpTest cleanup \\(PC=[xA-F0-9]+\\)\n\n" +
+		"(.|\n)*" +
+		"\n-+\n" +
+		"PANIC: fixture_test\\.go:[0-9]+: CleanupHelper\\.Test1\n\n" +
+		"(.|\n)*" +
+		"\\.\\.\\. Panic: Fixture has panicked \\(see related PANIC\\)\n$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *FixtureS) TestPanicOnSuiteCleanup(c *C) {
+	helper := CleanupHelper{panicOn: "SetUpSuite cleanup 2"}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output})
+	c.Check(result.FixturePanicked, Equals, 1)
+	c.Check(helper.calls[len(helper.calls)-2:], DeepEquals, []string{
+		"SetUpSuite cleanup 2", "SetUpSuite cleanup 1"})
+
+	expected := "(?s).*\n-+\n" +
+		"PANIC: fixture_test\\.go:[0-9]+: CleanupHelper\\.SetUpSuite\n\n" +
+		"\\.\\.\\. Panic: SetUpSuite cleanup 2 \\(PC=[xA-F0-9]+\\)\n.*$"
+	c.Check(output.value, Matches, expected)
+}
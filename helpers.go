@@ -1,6 +1,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -25,6 +26,7 @@ func (c *C) Failed() bool {
 // what went wrong. The higher level helper functions will fail the test
 // and do the logging properly.
 func (c *C) Fail() {
+	c.endFailure()
 	c.setStatus(failedSt)
 }
 
@@ -64,6 +66,26 @@ func (c *C) ExpectFailure(reason string) {
 	c.reason = reason
 }
 
+// Flaky informs that the running test is known to fail intermittently
+// for the provided reason, and that it should be retried up to maxRetries
+// times when it fails, overriding RunConf.Retries. A test which fails and
+// then succeeds on a retry is reported as FLAKY together with the logs of
+// the failed attempts, and isn't counted as succeeded. Flaky must be
+// called before the test fails to have any effect.
+func (c *C) Flaky(maxRetries int, reason string) {
+	if reason == "" {
+		panic("Missing reason why the test is flaky")
+	}
+	if c.kind != testKd {
+		panic("Flaky must be called from a test method")
+	}
+	for c.parent != nil {
+		c = c.parent
+	}
+	c.retries = maxRetries
+	c.flaky = reason
+}
+
 // Skip skips the running test for the provided reason. If run from within
 // SetUpTest, the individual test being set up will be skipped, and if run
 // from within SetUpSuite, the whole suite is skipped.
@@ -76,6 +98,129 @@ func (c *C) Skip(reason string) {
 	c.stopNow()
 }
 
+// Parallel signals that the running test may be run in parallel with
+// other tests of the same suite which also call Parallel. The test is
+// paused until all the tests in the suite which don't call Parallel have
+// finished running, and it's then resumed as soon as fewer than
+// RunConf.Parallelism parallel tests are running. SetUpTest runs before
+// the test gets paused, and TearDownTest runs after it finishes, in
+// parallel with the other tests. SetUpSuite and TearDownSuite still
+// run before and after all the tests of the suite. Parallel does nothing
+// when tests are repeated with RunConf.Count or RunConf.UntilFail, since
+// each iteration of a test waits for the previous one.
+func (c *C) Parallel() {
+	if c.kind != testKd {
+		panic("Parallel must be called from a test method")
+	}
+	if !strings.HasPrefix(c.method.Info.Name, "Test") {
+		panic("Parallel can't be used in benchmarks")
+	}
+	if c.parent != nil {
+		panic("Parallel can't be used in subtests")
+	}
+	if c.parallel {
+		if c.retried > 0 {
+			// Resumed already, in an earlier attempt.
+			return
+		}
+		panic("Parallel called multiple times")
+	}
+	if c.iteration > 0 {
+		return
+	}
+	c.timeoutm.Lock()
+	c.parallel = true
+	c.paused = true
+	c.timeoutm.Unlock()
+	c.StopTimer()
+	c.runner.state.callPaused(c, true)
+	c.runner.parallelPaused <- c
+	<-c.runner.parallelResume
+	c.runner.parallelSem <- true
+	c.runner.state.callPaused(c, false)
+	c.timeoutm.Lock()
+	c.paused = false
+	c.timeoutBase = time.Now()
+	c.timeoutm.Unlock()
+	c.wakeWatcher()
+	if c.runner.aborted() {
+		c.setStatus(missedSt)
+		c.stopNow()
+	}
+	c.StartTimer()
+}
+
+// SetTimeout sets the maximum time the running test may take, overriding
+// RunConf.TestTimeout. The time is counted from the start of the test, or
+// from when it was resumed if it called Parallel, and a zero duration
+// disables the timeout. A test still running after its timeout is reported
+// as timed out together with the stacks of all goroutines, and the runner
+// moves on without waiting for it. Within a subtest, SetTimeout sets the
+// timeout of the top-level test.
+func (c *C) SetTimeout(d time.Duration) {
+	if c.kind != testKd {
+		panic("SetTimeout must be called from a test method")
+	}
+	for c.parent != nil {
+		c = c.parent
+	}
+	c.timeoutm.Lock()
+	c.timeout = d
+	c.timeoutm.Unlock()
+	c.wakeWatcher()
+}
+
+// Context returns a context which is cancelled as soon as the running test
+// stops, be it by returning, by calling FailNow (or a helper such as Assert
+// which calls it), by panicking or by timing out, right before TearDownTest
+// is run. Within SetUpTest and TearDownTest it returns the context of the
+// test being set up or torn down. Within SetUpSuite and TearDownSuite it
+// returns a context which is cancelled right before TearDownSuite is run.
+func (c *C) Context() context.Context {
+	return c.scope.ctx
+}
+
+// Cleanup registers a function to be called when the running test
+// finishes, after TearDownTest, or when called from SetUpSuite or
+// TearDownSuite, when the suite finishes, after TearDownSuite. Cleanup
+// functions are called in the reverse order they were registered, even
+// if the test panicked or was stopped with FailNow, and panics within
+// them are reported against the test or fixture which registered them.
+func (c *C) Cleanup(f func()) {
+	c.scope.addCleanup(f, c.method)
+}
+
+// Run runs f as a subtest of the running test, named after the test
+// followed by a slash and the given name, as in "Suite.TestX/name". The
+// subtest has its own log, status and timer, and is reported and counted
+// in the results on its own. A failing subtest also fails its parent.
+//
+// The filter may select subtests by having one slash-separated expression
+// for each level of subtests after the one selecting tests, as in
+// "TestX/name". Subtests which aren't selected aren't run.
+//
+// Run returns once f returns or the subtest is otherwise stopped, as with
+// FailNow, and reports whether the subtest didn't fail.
+func (c *C) Run(name string, f func(c *C)) bool {
+	if c.kind != testKd {
+		panic("Run must be called from a test method")
+	}
+	if !strings.HasPrefix(c.method.Info.Name, "Test") {
+		panic("Run can't be used in benchmarks")
+	}
+	sub := c.runner.runSubtest(c, strings.Replace(name, " ", "_", -1), f)
+	if sub == nil {
+		return true
+	}
+	switch sub.status() {
+	case succeededSt, skippedSt:
+		return true
+	}
+	c.logString("Subtest failed: " + sub.testName)
+	c.Fail()
+	return false
+}
+
 // -----------------------------------------------------------------------
 // Basic logging.
 
@@ -0,0 +1,195 @@
+package check
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+// JUnit XML report, with one testsuite per suite and one testcase per test.
+
+type junitReport struct {
+	m      sync.Mutex
+	path   string
+	suites []*junitSuite
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Errors   int           `xml:"errors,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     string        `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Cases    []*junitCase `xml:"testcase"`
+	duration time.Duration
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Log     string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// Create a report to be written to path, listing the suites in the
+// given order, whether or not they get to run.
+func newJUnitReport(path string, suites []interface{}) *junitReport {
+	r := &junitReport{path: path}
+	for _, suite := range suites {
+		t := reflect.TypeOf(suite)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		r.suite(t.Name())
+	}
+	return r
+}
+
+// Return the named suite, adding it if it's not there yet.
+// Must be called with r.m held, if the report is in use.
+func (r *junitReport) suite(name string) *junitSuite {
+	for _, suite := range r.suites {
+		if suite.Name == name {
+			return suite
+		}
+	}
+	suite := &junitSuite{Name: name}
+	r.suites = append(r.suites, suite)
+	return suite
+}
+
+func (r *junitReport) RunStarted()                                {}
+func (r *junitReport) SuiteStarted(suite string)                  {}
+func (r *junitReport) CallStarted(call *CallInfo)                 {}
+func (r *junitReport) SuiteFinished(suite string, result *Result) {}
+
+// Record the finished call. Tests are always recorded, while fixtures
+// are only recorded when they fail or panic.
+func (r *junitReport) CallFinished(result *CallResult) {
+	tc := &junitCase{Classname: result.Suite, Name: result.Method}
+	if i := strings.Index(result.Name, "/"); i >= 0 {
+		// A subtest, named after its path within the test.
+		tc.Name += result.Name[i:]
+	}
+	if result.Iteration > 0 {
+		tc.Name += fmt.Sprintf(" (iteration %d)", result.Iteration)
+	}
+	log := result.Log
+	switch result.Status {
+	case CallSucceeded:
+		if result.Fixture {
+			return
+		}
+		if result.ExpectedFailure {
+			log = "Failed as expected: " + result.Reason + "\n" + log
+		}
+		tc.SystemOut = log
+	case CallFailed:
+		tc.Failure = &junitProblem{Message: "Failed", Type: "FAIL", Log: log}
+		if result.ExpectedFailure {
+			tc.Failure.Message = "Succeeded, but was expected to fail: " + result.Reason
+		}
+	case CallPanicked:
+		tc.Error = &junitProblem{Message: "Panicked", Type: "PANIC", Log: log}
+	case CallFixturePanicked:
+		// The fixture's panic is recorded on its own testcase.
+		tc.Skipped = &junitSkipped{Message: "Missed"}
+	case CallTimedOut:
+		tc.Error = &junitProblem{Message: "Timed out", Type: "TIMEOUT", Log: log}
+	case CallSkipped:
+		if result.Fixture {
+			return
+		}
+		tc.Skipped = &junitSkipped{Message: result.Reason}
+	case CallMissed:
+		tc.Skipped = &junitSkipped{Message: "Missed"}
+	default:
+		return
+	}
+	tc.Time = junitTime(result.Duration)
+
+	r.m.Lock()
+	suite := r.suite(tc.Classname)
+	suite.Cases = append(suite.Cases, tc)
+	suite.duration += result.Duration
+	r.m.Unlock()
+}
+
+// Write the report out once all the suites are done, failing the run
+// if it can't be written.
+func (r *junitReport) RunFinished(result *Result) {
+	if err := r.write(); err != nil && result.RunError == nil {
+		result.RunError = err
+	}
+}
+
+// Write the report out, once all the suites are done.
+func (r *junitReport) write() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	all := junitTestSuites{Suites: r.suites}
+	var duration time.Duration
+	for _, suite := range r.suites {
+		suite.Tests = len(suite.Cases)
+		suite.Failures, suite.Errors, suite.Skipped = 0, 0, 0
+		for _, tc := range suite.Cases {
+			switch {
+			case tc.Failure != nil:
+				suite.Failures++
+			case tc.Error != nil:
+				suite.Errors++
+			case tc.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+		suite.Time = junitTime(suite.duration)
+		all.Tests += suite.Tests
+		all.Failures += suite.Failures
+		all.Errors += suite.Errors
+		all.Skipped += suite.Skipped
+		duration += suite.duration
+	}
+	all.Time = junitTime(duration)
+	data, err := xml.MarshalIndent(&all, "", "\t")
+	if err == nil {
+		data = append([]byte(xml.Header), append(data, '\n')...)
+		err = ioutil.WriteFile(r.path, data, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot write JUnit report: %v", err)
+	}
+	return nil
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
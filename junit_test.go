@@ -0,0 +1,84 @@
+package check_test
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"regexp"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&junitS{})
+
+type junitS struct{}
+
+type JUnitTearDownHelper struct{}
+
+func (s *JUnitTearDownHelper) TearDownSuite(c *C) {
+	c.Fail()
+}
+
+func (s *JUnitTearDownHelper) TestPass(c *C) {}
+
+func (s *junitS) TestReport(c *C) {
+	path := filepath.Join(c.MkDir(), "junit.xml")
+	result, _ := runCapturing(&RunConf{JUnitFile: path, Filter: "Outcome|JUnit"},
+		&OutcomeHelper{}, &JUnitTearDownHelper{}, &SuccessHelper{})
+	c.Assert(result.RunError, IsNil)
+
+	data := readReport(c, path)
+	report := regexp.MustCompile(`time="[0-9.]+"`).ReplaceAllString(data, `time="0.000"`)
+	report = regexp.MustCompile(`(<(failure|error) message="[^"]+" type="[A-Z]+">)[^<]+(</(failure|error)>)`).ReplaceAllString(report, "$1...$3")
+	c.Check(report, Equals, xml.Header+`<testsuites tests="7" failures="2" errors="1" skipped="1" time="0.000">
+	<testsuite name="OutcomeHelper" tests="5" failures="1" errors="1" skipped="1" time="0.000">
+		<testcase name="TestExpectFailure" classname="OutcomeHelper" time="0.000">
+			<system-out>Failed as expected: Bug #1&#xA;</system-out>
+		</testcase>
+		<testcase name="TestFail" classname="OutcomeHelper" time="0.000">
+			<failure message="Failed" type="FAIL">...</failure>
+		</testcase>
+		<testcase name="TestPanic" classname="OutcomeHelper" time="0.000">
+			<error message="Panicked" type="PANIC">...</error>
+		</testcase>
+		<testcase name="TestPass" classname="OutcomeHelper" time="0.000">
+			<system-out>Passing&#xA;</system-out>
+		</testcase>
+		<testcase name="TestSkip" classname="OutcomeHelper" time="0.000">
+			<skipped message="Not now"></skipped>
+		</testcase>
+	</testsuite>
+	<testsuite name="JUnitTearDownHelper" tests="2" failures="1" errors="0" skipped="0" time="0.000">
+		<testcase name="TestPass" classname="JUnitTearDownHelper" time="0.000"></testcase>
+		<testcase name="TearDownSuite" classname="JUnitTearDownHelper" time="0.000">
+			<failure message="Failed" type="FAIL"></failure>
+		</testcase>
+	</testsuite>
+	<testsuite name="SuccessHelper" tests="0" failures="0" errors="0" skipped="0" time="0.000"></testsuite>
+</testsuites>
+`)
+	c.Check(data, Matches, `(?s).*<failure message="Failed" type="FAIL">Failing&#xA;check_test\.go:[0-9]+:.*`)
+	c.Check(data, Matches, `(?s).*\.\.\. Panic: Boom! .*`)
+}
+
+func (s *junitS) TestReportMissed(c *C) {
+	path := filepath.Join(c.MkDir(), "junit.xml")
+	runCapturing(&RunConf{JUnitFile: path}, &FixtureHelper{panicOn: "SetUpSuite"})
+	c.Check(readReport(c, path), Matches, `(?s).*<testsuite name="FixtureHelper" tests="3" failures="0" errors="1" skipped="2" .*`+
+		`<testcase name="SetUpSuite" classname="FixtureHelper" time="[0-9.]+">\s*<error message="Panicked" type="PANIC">.*`+
+		`<testcase name="Test1" classname="FixtureHelper" time="[0-9.]+">\s*<skipped message="Missed"></skipped>.*`)
+}
+
+func (s *junitS) TestReportSetUpTestPanic(c *C) {
+	path := filepath.Join(c.MkDir(), "junit.xml")
+	runCapturing(&RunConf{JUnitFile: path}, &FixtureHelper{panicOn: "SetUpTest"})
+	c.Check(readReport(c, path), Matches, `(?s).*<testsuite name="FixtureHelper" tests="3" failures="0" errors="1" skipped="2" .*`+
+		`<testcase name="SetUpTest" classname="FixtureHelper" time="[0-9.]+">\s*<error message="Panicked" type="PANIC">.*`+
+		`<testcase name="Test1" classname="FixtureHelper" time="[0-9.]+">\s*<skipped message="Missed"></skipped>.*`+
+		`<testcase name="Test2" classname="FixtureHelper" time="[0-9.]+">\s*<skipped message="Missed"></skipped>.*`)
+}
+
+func (s *junitS) TestReportWriteError(c *C) {
+	path := filepath.Join(c.MkDir(), "missing", "junit.xml")
+	result, _ := runCapturing(&RunConf{JUnitFile: path}, &SuccessHelper{})
+	c.Check(result.String(), Matches, "ERROR: Cannot write JUnit report: .*")
+}
@@ -1,11 +1,97 @@
 package check
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 )
 
+// -----------------------------------------------------------------------
+// Reporter interface, for following the progress of a run.
+
+// Reporter is notified of the progress of a run, as configured in
+// RunConf.Reporters.  Its methods are called one at a time, even when
+// tests or suites run in parallel.
+//
+// SuiteStarted and SuiteFinished are given the name of the suite type.
+// Calls of suite methods, including fixtures, are reported with
+// CallStarted and CallFinished.  Fixtures that succeed or get skipped
+// are reported as such too, so reporters interested only in tests
+// should check CallInfo.Fixture.
+type Reporter interface {
+	RunStarted()
+	SuiteStarted(suite string)
+	CallStarted(call *CallInfo)
+	CallFinished(result *CallResult)
+	SuiteFinished(suite string, result *Result)
+	RunFinished(result *Result)
+}
+
+// CallStatus is the outcome of a call of a suite method.
+type CallStatus int
+
+const (
+	CallSucceeded       CallStatus = succeededSt
+	CallFailed          CallStatus = failedSt
+	CallSkipped         CallStatus = skippedSt
+	CallPanicked        CallStatus = panickedSt
+	CallFixturePanicked CallStatus = fixturePanickedSt // A fixture of the test has panicked.
+	CallMissed          CallStatus = missedSt          // Not run, due to an earlier problem.
+	CallTimedOut        CallStatus = timedOutSt
+)
+
+var callStatusNames = []string{"PASS", "FAIL", "SKIP", "PANIC", "FIXTURE-PANIC", "MISS", "TIMEOUT"}
+
+func (status CallStatus) String() string {
+	if status >= 0 && int(status) < len(callStatusNames) {
+		return callStatusNames[status]
+	}
+	return fmt.Sprintf("CallStatus(%d)", int(status))
+}
+
+// CallInfo identifies a call of a suite method.
+type CallInfo struct {
+	Suite     string // Name of the suite type, as in "MySuite".
+	Method    string // Name of the method, as in "TestFoo" or "SetUpTest".
+	Name      string // As shown in reports, as in "MySuite.TestFoo/sub".
+	Test      string // Test the call is for, if any, as in "MySuite.TestFoo".
+	Fixture   bool
+	Iteration int    // Of a test run repeatedly, counting from 1.
+	File      string // Where the method is defined.
+	Line      int
+}
+
+// Failure is a failed check, error, or panic within a call.
+type Failure struct {
+	File    string
+	Line    int
+	Message string // As logged, without the location.
+}
+
+// CallResult is the outcome of a finished call of a suite method.
+type CallResult struct {
+	CallInfo
+	Status          CallStatus
+	Duration        time.Duration
+	Wall            time.Duration // Including SetUpTest and TearDownTest.
+	Log             string
+	Reason          string // As given to Skip or ExpectFailure.
+	ExpectedFailure bool   // Called ExpectFailure, so succeeded by failing.
+	Retries         int    // Failed attempts before this one.
+	Slow            bool   // Took longer than RunConf.SlowTest.
+	FailureFile     string // Where the first failed check or error was, if any.
+	FailureLine     int
+	Failures        []Failure
+	Timing          string // Duration, or benchmark results, as reported.
+
+	// The log is shared by a test and its fixtures, and is drained as
+	// problems get written to the output, so that each problem is
+	// reported with the log written since the last one.
+	logb *logger
+}
+
 // -----------------------------------------------------------------------
 // Output writer manages atomic output writing according to settings.
 
@@ -15,6 +101,7 @@ type outputWriter struct {
 	wroteCallProblemLast bool
 	Stream               bool
 	Verbose              bool
+	Color                bool
 }
 
 func newOutputWriter(writer io.Writer, stream, verbose bool) *outputWriter {
@@ -28,46 +115,91 @@ func (ow *outputWriter) Write(content []byte) (n int, err error) {
 	return
 }
 
-func (ow *outputWriter) WriteCallStarted(label string, c *C) {
+func (ow *outputWriter) RunStarted()                                {}
+func (ow *outputWriter) SuiteStarted(suite string)                  {}
+func (ow *outputWriter) SuiteFinished(suite string, result *Result) {}
+func (ow *outputWriter) RunFinished(result *Result)                 {}
+
+func (ow *outputWriter) CallStarted(call *CallInfo) {
+	ow.WriteCallStarted("START", call)
+}
+
+func (ow *outputWriter) CallFinished(result *CallResult) {
+	switch result.Status {
+	case CallSucceeded:
+		if result.ExpectedFailure {
+			ow.WriteCallSuccess("FAIL EXPECTED", result)
+		} else if result.Retries > 0 {
+			ow.WriteCallProblem("FLAKY", result)
+		} else if result.Slow {
+			ow.WriteCallProblem("SLOW", result)
+		} else {
+			ow.WriteCallSuccess("PASS", result)
+		}
+	case CallSkipped:
+		ow.WriteCallSuccess("SKIP", result)
+	case CallFailed:
+		ow.WriteCallProblem("FAIL", result)
+	case CallPanicked:
+		ow.WriteCallProblem("PANIC", result)
+	case CallFixturePanicked:
+		// That's a test reporting that its fixture has panicked.
+		// The fixture call which caused the panic itself was
+		// reported already. We'll report to aid debugging.
+		ow.WriteCallProblem("PANIC", result)
+	case CallMissed:
+		ow.WriteCallSuccess("MISS", result)
+	case CallTimedOut:
+		ow.WriteCallProblem("TIMEOUT", result)
+	}
+}
+
+func (ow *outputWriter) WriteCallStarted(label string, call *CallInfo) {
 	if ow.Stream {
-		header := renderCallHeader(label, c, "", "\n")
+		header := renderCallHeader(ow.label(label), call, "", "\n")
 		ow.m.Lock()
 		ow.writer.Write([]byte(header))
 		ow.m.Unlock()
 	}
 }
 
-func (ow *outputWriter) WriteCallProblem(label string, c *C) {
+func (ow *outputWriter) WriteCallProblem(label string, result *CallResult) {
 	var prefix string
 	if !ow.Stream {
 		prefix = "\n-----------------------------------" +
 			"-----------------------------------\n"
 	}
-	header := renderCallHeader(label, c, prefix, "\n\n")
+	header := renderCallHeader(ow.label(label), &result.CallInfo, prefix, "\n\n")
 	ow.m.Lock()
 	ow.wroteCallProblemLast = true
 	ow.writer.Write([]byte(header))
 	if !ow.Stream {
-		c.logb.WriteTo(ow.writer)
+		if ow.Color {
+			var log bytes.Buffer
+			result.logb.WriteTo(&log)
+			io.WriteString(ow.writer, colorizeLog(log.String()))
+		} else {
+			result.logb.WriteTo(ow.writer)
+		}
 	}
 	ow.m.Unlock()
 }
 
-func (ow *outputWriter) WriteCallSuccess(label string, c *C) {
-	if ow.Stream || (ow.Verbose && c.kind == testKd) {
+func (ow *outputWriter) WriteCallSuccess(label string, result *CallResult) {
+	if ow.Stream || (ow.Verbose && !result.Fixture) {
 		// TODO Use a buffer here.
 		var suffix string
-		if c.reason != "" {
-			suffix = " (" + c.reason + ")"
+		if result.Reason != "" {
+			suffix = " (" + result.Reason + ")"
 		}
-		if c.status() == succeededSt {
-			suffix += "\t" + c.timerString()
+		if result.Status == CallSucceeded {
+			suffix += "\t" + result.Timing
 		}
 		suffix += "\n"
 		if ow.Stream {
 			suffix += "\n"
 		}
-		header := renderCallHeader(label, c, "", suffix)
+		header := renderCallHeader(ow.label(label), &result.CallInfo, "", suffix)
 		ow.m.Lock()
 		// Resist temptation of using line as prefix above due to race.
 		if !ow.Stream && ow.wroteCallProblemLast {
@@ -81,8 +213,18 @@ func (ow *outputWriter) WriteCallSuccess(label string, c *C) {
 	}
 }
 
-func renderCallHeader(label string, c *C, prefix, suffix string) string {
-	pc := c.method.PC()
-	return fmt.Sprintf("%s%s: %s: %s%s", prefix, label, niceFuncPath(pc),
-		niceFuncName(pc), suffix)
+func (ow *outputWriter) label(label string) string {
+	if color := labelColor(label); ow.Color && color != "" {
+		return colorize(color, label)
+	}
+	return label
+}
+
+func renderCallHeader(label string, call *CallInfo, prefix, suffix string) string {
+	name := call.Name
+	if call.Iteration > 0 {
+		name += fmt.Sprintf(" (iteration %d)", call.Iteration)
+	}
+	return fmt.Sprintf("%s%s: %s:%d: %s%s", prefix, label, nicePath(call.File),
+		call.Line, name, suffix)
 }
@@ -40,7 +40,7 @@ func (s *reporterS) TestWriteCallStartedWithStreamFlag(c *C) {
 	dummyVerbose := true
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 
-	o.WriteCallStarted(testLabel, c)
+	o.WriteCallStarted(testLabel, c.CallInfo())
 	expected := fmt.Sprintf("%s: %s:\\d+: %s\n", testLabel, s.testFile, c.TestName())
 	c.Assert(output.value, Matches, expected)
 }
@@ -53,7 +53,7 @@ func (s *reporterS) TestWriteCallStartedWithoutStreamFlag(c *C) {
 	dummyVerbose := true
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 
-	o.WriteCallStarted(dummyLabel, c)
+	o.WriteCallStarted(dummyLabel, c.CallInfo())
 	c.Assert(output.value, Equals, "")
 }
 
@@ -65,7 +65,7 @@ func (s *reporterS) TestWriteCallProblemWithStreamFlag(c *C) {
 	dummyVerbose := true
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 
-	o.WriteCallProblem(testLabel, c)
+	o.WriteCallProblem(testLabel, c.CallResult())
 	expected := fmt.Sprintf("%s: %s:\\d+: %s\n\n", testLabel, s.testFile, c.TestName())
 	c.Assert(output.value, Matches, expected)
 }
@@ -78,7 +78,7 @@ func (s *reporterS) TestWriteCallProblemWithoutStreamFlag(c *C) {
 	dummyVerbose := true
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 
-	o.WriteCallProblem(testLabel, c)
+	o.WriteCallProblem(testLabel, c.CallResult())
 	expected := fmt.Sprintf(""+
 		"\n"+
 		"----------------------------------------------------------------------\n"+
@@ -96,7 +96,7 @@ func (s *reporterS) TestWriteCallProblemWithoutStreamFlagWithLog(c *C) {
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 
 	c.Log(testLog)
-	o.WriteCallProblem(testLabel, c)
+	o.WriteCallProblem(testLabel, c.CallResult())
 	expected := fmt.Sprintf(""+
 		"\n"+
 		"----------------------------------------------------------------------\n"+
@@ -112,7 +112,7 @@ func (s *reporterS) TestWriteCallSuccessWithStreamFlag(c *C) {
 	dummyVerbose := true
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 
-	o.WriteCallSuccess(testLabel, c)
+	o.WriteCallSuccess(testLabel, c.CallResult())
 	expected := fmt.Sprintf("%s: %s:\\d+: %s\t\\d\\.\\d+s\n\n", testLabel, s.testFile, c.TestName())
 	c.Assert(output.value, Matches, expected)
 }
@@ -127,7 +127,7 @@ func (s *reporterS) TestWriteCallSuccessWithStreamFlagAndReason(c *C) {
 	o := NewOutputWriter(&output, stream, dummyVerbose)
 	c.FakeSkip(testReason)
 
-	o.WriteCallSuccess(testLabel, c)
+	o.WriteCallSuccess(testLabel, c.CallResult())
 	expected := fmt.Sprintf("%s: %s:\\d+: %s \\(%s\\)\t\\d\\.\\d+s\n\n",
 		testLabel, s.testFile, c.TestName(), testReason)
 	c.Assert(output.value, Matches, expected)
@@ -141,7 +141,7 @@ func (s *reporterS) TestWriteCallSuccessWithoutStreamFlagWithVerboseFlag(c *C) {
 
 	o := NewOutputWriter(&output, stream, verbose)
 
-	o.WriteCallSuccess(testLabel, c)
+	o.WriteCallSuccess(testLabel, c.CallResult())
 	expected := fmt.Sprintf("%s: %s:\\d+: %s\t\\d\\.\\d+s\n", testLabel, s.testFile, c.TestName())
 	c.Assert(output.value, Matches, expected)
 }
@@ -154,6 +154,83 @@ func (s *reporterS) TestWriteCallSuccessWithoutStreamFlagWithoutVerboseFlag(c *C
 
 	o := NewOutputWriter(&output, stream, verbose)
 
-	o.WriteCallSuccess(testLabel, c)
+	o.WriteCallSuccess(testLabel, c.CallResult())
 	c.Assert(output.value, Equals, "")
 }
+
+type recordingReporter struct {
+	events  []string
+	results []*CallResult
+}
+
+func (r *recordingReporter) RunStarted() {
+	r.events = append(r.events, "RunStarted")
+}
+
+func (r *recordingReporter) SuiteStarted(suite string) {
+	r.events = append(r.events, "SuiteStarted "+suite)
+}
+
+func (r *recordingReporter) CallStarted(call *CallInfo) {
+	r.events = append(r.events, "CallStarted "+call.Name)
+}
+
+func (r *recordingReporter) CallFinished(result *CallResult) {
+	r.events = append(r.events, fmt.Sprintf("CallFinished %s %s", result.Name, result.Status))
+	r.results = append(r.results, result)
+}
+
+func (r *recordingReporter) SuiteFinished(suite string, result *Result) {
+	r.events = append(r.events, fmt.Sprintf("SuiteFinished %s %s", suite, result))
+}
+
+func (r *recordingReporter) RunFinished(result *Result) {
+	r.events = append(r.events, fmt.Sprintf("RunFinished %s", result))
+}
+
+func (s *reporterS) TestReporters(c *C) {
+	helper := OutcomeHelper{}
+	reporter := recordingReporter{}
+	runCapturing(&RunConf{Reporters: []Reporter{&reporter}, Filter: "TestFail|TestSkip"}, &helper)
+
+	c.Check(reporter.events, DeepEquals, []string{
+		"RunStarted",
+		"SuiteStarted OutcomeHelper",
+		"CallStarted OutcomeHelper.TestFail",
+		"CallStarted OutcomeHelper.SetUpTest",
+		"CallFinished OutcomeHelper.SetUpTest PASS",
+		"CallFinished OutcomeHelper.TestFail FAIL",
+		"CallStarted OutcomeHelper.TestSkip",
+		"CallStarted OutcomeHelper.SetUpTest",
+		"CallFinished OutcomeHelper.SetUpTest PASS",
+		"CallFinished OutcomeHelper.TestSkip SKIP",
+		"SuiteFinished OutcomeHelper OOPS: 0 passed, 1 skipped, 1 FAILED",
+		"RunFinished OOPS: 0 passed, 1 skipped, 1 FAILED",
+	})
+
+	c.Assert(reporter.results, HasLen, 4)
+	setUp, fail, skip := reporter.results[0], reporter.results[1], reporter.results[3]
+	c.Check(setUp.Fixture, Equals, true)
+	c.Check(setUp.Test, Equals, "OutcomeHelper.TestFail")
+
+	c.Check(fail.Suite, Equals, "OutcomeHelper")
+	c.Check(fail.Method, Equals, "TestFail")
+	c.Check(fail.Test, Equals, "OutcomeHelper.TestFail")
+	c.Check(fail.Fixture, Equals, false)
+	c.Check(filepath.Base(fail.File), Equals, "check_test.go")
+	c.Check(fail.Line > 0, Equals, true)
+	c.Check(filepath.Base(fail.FailureFile), Equals, "check_test.go")
+	c.Check(fail.FailureLine, Equals, helper.failLine)
+	c.Check(fail.Log, Matches, "(?s)Failing\n.*obtained int = 1\n.*")
+	c.Check(fail.Duration > 0, Equals, true)
+
+	c.Check(skip.Status, Equals, CallSkipped)
+	c.Check(skip.Reason, Equals, "Not now")
+	c.Check(skip.FailureFile, Equals, "")
+}
+
+func (s *reporterS) TestCallStatusString(c *C) {
+	c.Check(CallSucceeded.String(), Equals, "PASS")
+	c.Check(CallTimedOut.String(), Equals, "TIMEOUT")
+	c.Check(CallStatus(42).String(), Equals, "CallStatus(42)")
+}
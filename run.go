@@ -2,9 +2,16 @@ package check
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -42,12 +49,75 @@ var (
 	newBenchMem    = flag.Bool("check.bmem", false, "Report memory benchmarks")
 	newListFlag    = flag.Bool("check.list", false, "List the names of all tests that will be run")
 	newWorkFlag    = flag.Bool("check.work", false, "Display and do not remove the test working directory")
+	newParallel    = flag.Int("check.parallel", 0, "Maximum number of tests calling Parallel to run simultaneously (defaults to GOMAXPROCS)")
+	newPSuites     = flag.Int("check.psuites", 1, "Number of suites to run concurrently")
+	newTimeout     = flag.Duration("check.timeout", 0, "Maximum time each test may take (0 means no limit)")
+	newTimeoutStop = flag.Bool("check.timeoutabort", false, "Skip the remaining tests of a suite after a test times out")
+	newCount       = flag.Int("check.count", 1, "Run each test this many times")
+	newUntilFail   = flag.Bool("check.untilfail", false, "Repeat each test until it fails, up to check.count times if set")
+	newRetries     = flag.Int("check.retries", 0, "Number of times failed tests are retried")
+	newShard       = flag.String("check.shard", "", "Run only the given shard of the tests, as i/n with i counting from 0")
+	newFailFast    = flag.Bool("check.failfast", false, "Stop the run at the first failed test")
+	newShuffle     = flag.String("check.shuffle", "off", "Shuffle the order of tests and suites: on, off, or the seed to shuffle with")
+	newJUnitFile   = flag.String("check.junit", "", "Write a JUnit XML report to the given file")
+	newJSON        = flag.Bool("check.json", false, "Write the events of the run to stdout as JSON, in the format of go tool test2json")
+	newGitHub      = flag.Bool("check.github", false, "Annotate failures with GitHub Actions workflow commands on stdout")
+	newCodeQuality = flag.String("check.codequality", "", "Write a GitLab Code Quality report of failures to the given file")
+	newTAP         = flag.Bool("check.tap", false, "Write the results of the run to stdout in the Test Anything Protocol")
+	newSlowest     = flag.Int("check.slowest", 0, "Report this many of the slowest tests, and the time spent in suite fixtures")
+	newSlowTest    = flag.Duration("check.slow", 0, "Warn about tests taking longer than this (0 means no limit)")
+	newSlowFail    = flag.Bool("check.slowfail", false, "Fail tests taking longer than check.slow instead of warning")
+	newColor       = flag.String("check.color", "auto", "Color the output: auto, always or never")
+	newSubtests    = flag.Bool("check.subtests", false, "Report each suite and test to the testing package as a subtest")
 )
 
 // TestingT runs all test suites registered with the Suite function,
 // printing results to stdout, and reporting any failures back to
-// the "testing" package.
+// the "testing" package. With the -check.subtests flag, it behaves
+// like TestingSubtests.
 func TestingT(testingT *testing.T) {
+	testingT.Helper()
+	if *newSubtests {
+		TestingSubtests(testingT)
+		return
+	}
+	conf := flagRunConf()
+	if *oldListFlag || *newListFlag {
+		printList(conf)
+		return
+	}
+	result := RunAll(conf)
+	println(result.String())
+	if !result.Passed() {
+		testingT.Fail()
+	}
+}
+
+// TestingSubtests runs all test suites registered with the Suite function
+// like TestingT, but reports each suite and each of its tests back to the
+// "testing" package as a subtest named after them, as in Suite/TestMethod.
+// That lets go test's -run, -v and -json options, and the tools built on
+// them, see every test individually. Failures are reported with the log
+// of the test, and skipped tests are skipped. SetUpSuite only runs once
+// one of the tests of the suite runs, so it doesn't run for suites whose
+// tests are all deselected by -run. Suites run one after the other, and
+// Count and UntilFail are ignored, as go test's -count does that job.
+func TestingSubtests(testingT *testing.T) {
+	testingT.Helper()
+	conf := flagRunConf()
+	if *oldListFlag || *newListFlag {
+		printList(conf)
+		return
+	}
+	result := runSuitesT(testingT, allSuites, conf)
+	println(result.String())
+	if !result.Passed() {
+		testingT.Fail()
+	}
+}
+
+// Build the run configuration from the command line flags.
+func flagRunConf() *RunConf {
 	benchTime := *newBenchTime
 	if benchTime == 1*time.Second {
 		benchTime = *oldBenchTime
@@ -60,43 +130,279 @@ func TestingT(testingT *testing.T) {
 		BenchmarkTime: benchTime,
 		BenchmarkMem:  *newBenchMem,
 		KeepWorkDir:   *oldWorkFlag || *newWorkFlag,
+		Parallelism:   *newParallel,
+
+		SuiteParallelism: *newPSuites,
+		TestTimeout:      *newTimeout,
+		AbortOnTimeout:   *newTimeoutStop,
+		Shuffle:          *newShuffle,
+		Count:            *newCount,
+		UntilFail:        *newUntilFail,
+		Retries:          *newRetries,
+		Shard:            *newShard,
+		FailFast:         *newFailFast,
+		JUnitFile:        *newJUnitFile,
+		CodeQualityFile:  *newCodeQuality,
+		Color:            *newColor,
+		Slowest:          *newSlowest,
+		SlowTest:         *newSlowTest,
+		SlowTestFails:    *newSlowFail,
 	}
-	if *oldListFlag || *newListFlag {
-		w := bufio.NewWriter(os.Stdout)
-		for _, name := range ListAll(conf) {
-			fmt.Fprintln(w, name)
-		}
-		w.Flush()
-		return
+	if *newGitHub {
+		conf.GitHubWriter = os.Stdout
 	}
-	result := RunAll(conf)
-	println(result.String())
-	if !result.Passed() {
-		testingT.Fail()
+	if *newJSON {
+		conf.Output = ioutil.Discard
+		conf.EventWriter = os.Stdout
+	}
+	if *newTAP {
+		conf.Output = ioutil.Discard
+		conf.TAPWriter = os.Stdout
+	}
+	return conf
+}
+
+func printList(conf *RunConf) {
+	w := bufio.NewWriter(os.Stdout)
+	for _, name := range ListAll(conf) {
+		fmt.Fprintln(w, name)
 	}
+	w.Flush()
 }
 
 // RunAll runs all test suites registered with the Suite function, using the
 // provided run configuration.
 func RunAll(runConf *RunConf) *Result {
+	return runSuites(allSuites, runConf)
+}
+
+func runSuites(suites []interface{}, runConf *RunConf) *Result {
+	suites, runConf, err := shuffleSuites(suites, runConf)
+	if err != nil {
+		return &Result{RunError: err}
+	}
+	state := newRunState(runConf, suites)
+	var result *Result
+	if runConf != nil && runConf.SuiteParallelism > 1 {
+		result = runSuitesParallel(suites, runConf, state)
+	} else {
+		result = &Result{}
+		for _, suite := range suites {
+			result.Add(runSuite(suite, runConf, state))
+		}
+	}
+	state.finish(result)
+	return result
+}
+
+// Run the suite as part of a run of many, sharing their state.
+func runSuite(suite interface{}, runConf *RunConf, state *runState) *Result {
+	runner := newSuiteRunner(suite, runConf)
+	runner.state = state
+	return runner.run()
+}
+
+// Shuffle the order of suites if runConf.Shuffle is enabled. The returned
+// configuration has the seed in use, so that tests within each suite are
+// shuffled with it too and any given order may be replayed.
+func shuffleSuites(suites []interface{}, runConf *RunConf) ([]interface{}, *RunConf, error) {
+	if runConf == nil {
+		return suites, runConf, nil
+	}
+	seed, shuffle, err := parseShuffle(runConf.Shuffle)
+	if err != nil || !shuffle {
+		return suites, runConf, err
+	}
+	conf := *runConf
+	conf.Shuffle = strconv.FormatInt(seed, 10)
+	shuffled := make([]interface{}, len(suites))
+	for i, j := range rand.New(rand.NewSource(seed)).Perm(len(suites)) {
+		shuffled[i] = suites[j]
+	}
+	return shuffled, &conf, nil
+}
+
+type suiteRun struct {
+	output bytes.Buffer
+	result *Result
+	done   chan bool
+}
+
+// Run the suites on a pool of runConf.SuiteParallelism workers. The output
+// of each suite is buffered while it runs, and written out in registration
+// order once the suite is done, so that suites don't interleave their output.
+func runSuitesParallel(suites []interface{}, runConf *RunConf, state *runState) *Result {
+	output := runConf.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	runs := make([]*suiteRun, len(suites))
+	for i := range runs {
+		runs[i] = &suiteRun{done: make(chan bool)}
+	}
+	next := make(chan int)
+	go func() {
+		for i := range suites {
+			next <- i
+		}
+		close(next)
+	}()
+	color := "never"
+	if colored, _ := parseColor(runConf.Color, output); colored {
+		color = "always"
+	}
+	for w := 0; w < runConf.SuiteParallelism; w++ {
+		go func() {
+			for i := range next {
+				conf := *runConf
+				conf.Output = &runs[i].output
+				conf.Color = color
+				runs[i].result = runSuite(suites[i], &conf, state)
+				close(runs[i].done)
+			}
+		}()
+	}
 	result := Result{}
-	for _, suite := range allSuites {
-		result.Add(Run(suite, runConf))
+	for _, run := range runs {
+		<-run.done
+		output.Write(run.output.Bytes())
+		result.Add(run.result)
 	}
 	return &result
 }
 
+// Run the suites as subtests of t, one after the other.
+func runSuitesT(t *testing.T, suites []interface{}, runConf *RunConf) *Result {
+	t.Helper()
+	suites, runConf, err := shuffleSuites(suites, runConf)
+	if err != nil {
+		return &Result{RunError: err}
+	}
+	state := newRunState(runConf, suites)
+	result := Result{}
+	for _, suite := range suites {
+		runner := newSuiteRunner(suite, runConf)
+		runner.state = state
+		result.Add(runner.runT(t))
+	}
+	state.finish(&result)
+	return &result
+}
+
+// Run the suite as a subtest of t, with each test as a subtest of it.
+// SetUpSuite runs within the first test which gets to run, and tests which
+// call Parallel are run as parallel subtests, so TearDownSuite runs after
+// the suite subtest is done.
+func (runner *suiteRunner) runT(t *testing.T) *Result {
+	t.Helper()
+	if runner.tracker.result.RunError != nil || len(runner.tests) == 0 {
+		return &runner.tracker.result
+	}
+	runner.testingT = true
+	runner.count = 1
+	runner.untilFail = false
+	runner.tracker.start()
+	runner.state.suiteStarted(runner.tests[0].suiteName())
+	runner.suiteScope = newCallScope(context.Background())
+
+	var setUp *C
+	setUpRan := false
+	setUpSt := funcStatus(succeededSt)
+	t.Run(runner.tests[0].suiteName(), func(st *testing.T) {
+		st.Helper()
+		for _, method := range runner.tests {
+			method := method
+			st.Run(method.Info.Name, func(t *testing.T) {
+				t.Helper()
+				if !setUpRan {
+					setUpRan = true
+					if runner.aborted() {
+						setUpSt = missedSt
+					} else if !runner.checkFixtureArgs() {
+						setUpSt = missedSt
+						st.Error("Fixtures with bad arguments (see related PANIC)")
+					} else {
+						setUp = runner.runFixture(runner.setUpSuite, "", nil)
+						if setUp != nil {
+							setUpSt = setUp.status()
+						}
+						if setUpSt != succeededSt && setUpSt != skippedSt {
+							st.Error("SetUpSuite didn't succeed (see related report)")
+						}
+					}
+				}
+				if setUpSt == skippedSt {
+					runner.skipTests(skippedSt, []*methodType{method})
+					t.Skip(setUp.reason)
+				}
+				if setUpSt != succeededSt || runner.aborted() {
+					runner.skipTests(missedSt, []*methodType{method})
+					t.Skip("Missed after an earlier problem")
+				}
+				n := len(runner.parallelTests)
+				c := runner.runTest(method, 0, false)
+				if len(runner.parallelTests) > n {
+					t.Parallel()
+					c = <-c.done
+				} else if c.status() == fixturePanickedSt {
+					runner.abort()
+				}
+				reportT(t, c)
+			})
+		}
+		close(runner.parallelResume)
+	})
+
+	runner.suiteScope.cancel()
+	if setUpRan && setUpSt != missedSt {
+		c := runner.runFixture(runner.tearDownSuite, "", nil)
+		if c != nil && c.status() != succeededSt {
+			t.Errorf("%s didn't succeed (see related report)", c.method)
+		}
+		runner.runSuiteCleanups()
+	}
+	runner.wrapUp()
+	return &runner.tracker.result
+}
+
+// Report the outcome of the test call back to the "testing" package.
+func reportT(t *testing.T, c *C) {
+	t.Helper()
+	switch c.status() {
+	case succeededSt:
+		if c.mustFail {
+			t.Log("Failed as expected: " + c.reason)
+		} else if c.retried > 0 {
+			t.Log("Flaky, succeeded after failing:\n" + strings.TrimRight(c.logb.String(), "\n"))
+		} else if c.slow {
+			t.Log(strings.TrimRight(c.logb.String(), "\n"))
+		} else if c.N > 0 && strings.HasPrefix(c.method.Info.Name, "Benchmark") {
+			t.Log(c.timerString())
+		}
+	case skippedSt:
+		t.Skip(c.reason)
+	case missedSt:
+		t.Skip("Missed after an earlier problem")
+	default:
+		t.Error("\n" + strings.TrimRight(c.logb.String(), "\n"))
+	}
+}
+
 // Run runs the provided test suite using the provided run configuration.
 func Run(suite interface{}, runConf *RunConf) *Result {
-	runner := newSuiteRunner(suite, runConf)
-	return runner.run()
+	suites := []interface{}{suite}
+	state := newRunState(runConf, suites)
+	result := runSuite(suite, runConf, state)
+	state.finish(result)
+	return result
 }
 
 // ListAll returns the names of all the test functions registered with the
 // Suite function that will be run with the provided run configuration.
 func ListAll(runConf *RunConf) []string {
 	var names []string
-	for _, suite := range allSuites {
+	suites, runConf, _ := shuffleSuites(allSuites, runConf)
+	for _, suite := range suites {
 		names = append(names, List(suite, runConf)...)
 	}
 	return names
@@ -123,7 +429,26 @@ func (r *Result) Add(other *Result) {
 	r.Panicked += other.Panicked
 	r.FixturePanicked += other.FixturePanicked
 	r.ExpectedFailures += other.ExpectedFailures
+	r.Flaky += other.Flaky
+	r.TimedOut += other.TimedOut
 	r.Missed += other.Missed
+	if r.Shuffle == "" {
+		r.Shuffle = other.Shuffle
+	}
+	r.Stopped = r.Stopped || other.Stopped
+	r.Tests = append(r.Tests, other.Tests...)
+	for name, counts := range other.Repeats {
+		if r.Repeats == nil {
+			r.Repeats = make(map[string]RepeatCounts)
+		}
+		sum := r.Repeats[name]
+		if sum.FirstFailure == 0 {
+			sum.FirstFailure = counts.FirstFailure
+		}
+		sum.Succeeded += counts.Succeeded
+		sum.Failed += counts.Failed
+		r.Repeats[name] = sum
+	}
 	if r.WorkDir != "" && other.WorkDir != "" {
 		r.WorkDir += ":" + other.WorkDir
 	} else if other.WorkDir != "" {
@@ -133,8 +458,8 @@ func (r *Result) Add(other *Result) {
 
 func (r *Result) Passed() bool {
 	return (r.Failed == 0 && r.Panicked == 0 &&
-		r.FixturePanicked == 0 && r.Missed == 0 &&
-		r.RunError == nil)
+		r.FixturePanicked == 0 && r.TimedOut == 0 &&
+		r.Missed == 0 && r.RunError == nil)
 }
 
 func (r *Result) String() string {
@@ -144,7 +469,7 @@ func (r *Result) String() string {
 
 	var value string
 	if r.Failed == 0 && r.Panicked == 0 && r.FixturePanicked == 0 &&
-		r.Missed == 0 {
+		r.TimedOut == 0 && r.Missed == 0 {
 		value = "OK: "
 	} else {
 		value = "OOPS: "
@@ -156,6 +481,9 @@ func (r *Result) String() string {
 	if r.ExpectedFailures != 0 {
 		value += fmt.Sprintf(", %d expected failures", r.ExpectedFailures)
 	}
+	if r.Flaky != 0 {
+		value += fmt.Sprintf(", %d FLAKY", r.Flaky)
+	}
 	if r.Failed != 0 {
 		value += fmt.Sprintf(", %d FAILED", r.Failed)
 	}
@@ -165,11 +493,32 @@ func (r *Result) String() string {
 	if r.FixturePanicked != 0 {
 		value += fmt.Sprintf(", %d FIXTURE-PANICKED", r.FixturePanicked)
 	}
+	if r.TimedOut != 0 {
+		value += fmt.Sprintf(", %d TIMED OUT", r.TimedOut)
+	}
 	if r.Missed != 0 {
 		value += fmt.Sprintf(", %d MISSED", r.Missed)
 	}
+	var repeated []string
+	for name, counts := range r.Repeats {
+		if counts.Failed != 0 {
+			repeated = append(repeated, name)
+		}
+	}
+	sort.Strings(repeated)
+	for _, name := range repeated {
+		counts := r.Repeats[name]
+		value += fmt.Sprintf("\nREPEAT %s: %d passed, %d FAILED (first at iteration %d)",
+			name, counts.Succeeded, counts.Failed, counts.FirstFailure)
+	}
+	if r.Stopped && r.Missed > 0 {
+		value += "\nSTOPPED: at the first failure, remaining tests were missed"
+	}
 	if r.WorkDir != "" {
 		value += "\nWORK=" + r.WorkDir
 	}
+	if r.Shuffle != "" {
+		value += "\nSHUFFLE=" + r.Shuffle
+	}
 	return value
 }
@@ -4,10 +4,17 @@ package check_test
 
 import (
 	"errors"
+	"fmt"
 	. "gopkg.in/check.v1"
 	"os"
+	"os/exec"
 	"regexp"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"testing"
+	"time"
 )
 
 var runnerS = Suite(&RunS{})
@@ -108,6 +115,8 @@ func (s *RunS) TestAdd(c *C) {
 		FixturePanicked:  5,
 		Missed:           6,
 		ExpectedFailures: 7,
+		TimedOut:         8,
+		Flaky:            9,
 	}
 	result.Add(&Result{
 		Succeeded:        10,
@@ -117,6 +126,8 @@ func (s *RunS) TestAdd(c *C) {
 		FixturePanicked:  50,
 		Missed:           60,
 		ExpectedFailures: 70,
+		TimedOut:         80,
+		Flaky:            90,
 	})
 	c.Check(result.Succeeded, Equals, 11)
 	c.Check(result.Skipped, Equals, 22)
@@ -125,9 +136,58 @@ func (s *RunS) TestAdd(c *C) {
 	c.Check(result.FixturePanicked, Equals, 55)
 	c.Check(result.Missed, Equals, 66)
 	c.Check(result.ExpectedFailures, Equals, 77)
+	c.Check(result.TimedOut, Equals, 88)
+	c.Check(result.Flaky, Equals, 99)
 	c.Check(result.RunError, IsNil)
 }
 
+func (s *RunS) TestAddRepeats(c *C) {
+	result := &Result{}
+	result.Add(&Result{Repeats: map[string]RepeatCounts{
+		"S.TestA": {Succeeded: 1, Failed: 2, FirstFailure: 1},
+		"S.TestB": {Succeeded: 3},
+	}})
+	result.Add(&Result{Repeats: map[string]RepeatCounts{
+		"S.TestB": {Succeeded: 1, Failed: 1, FirstFailure: 2},
+	}})
+	c.Check(result.Repeats, DeepEquals, map[string]RepeatCounts{
+		"S.TestA": {Succeeded: 1, Failed: 2, FirstFailure: 1},
+		"S.TestB": {Succeeded: 4, Failed: 1, FirstFailure: 2},
+	})
+}
+
+func (s *RunS) TestAddTests(c *C) {
+	result := &Result{}
+	result.Add(&Result{Tests: []TestResult{{CallResult: CallResult{Status: CallFailed}}}})
+	result.Add(&Result{})
+	result.Add(&Result{Tests: []TestResult{{CallResult: CallResult{Status: CallSucceeded}}}})
+	c.Assert(result.Tests, HasLen, 2)
+	c.Check(result.Tests[0].Status, Equals, CallFailed)
+	c.Check(result.Tests[1].Status, Equals, CallSucceeded)
+}
+
+func (s *RunS) TestTests(c *C) {
+	output := String{}
+	failHelper := FailHelper{}
+	result := RunSuites([]interface{}{&SuccessHelper{}, &failHelper, &FixtureHelper{panicOn: "SetUpSuite"}}, &RunConf{Output: &output})
+	c.Assert(result.Tests, HasLen, 4)
+
+	pass, fail, missed := result.Tests[0], result.Tests[1], result.Tests[2]
+	c.Check(pass.Suite, Equals, "SuccessHelper")
+	c.Check(pass.Name, Equals, "SuccessHelper.TestLogAndSucceed")
+	c.Check(pass.Status, Equals, CallSucceeded)
+	c.Check(pass.Log, Equals, "Expected success!\n")
+	c.Check(pass.Benchmark, IsNil)
+
+	c.Check(fail.Name, Equals, "FailHelper.TestLogAndFail")
+	c.Check(fail.Status, Equals, CallFailed)
+	c.Check(fail.Line, Equals, failHelper.testLine)
+	c.Check(fail.Log, Equals, "Expected failure!\n")
+
+	c.Check(missed.Name, Equals, "FixtureHelper.Test1")
+	c.Check(missed.Status, Equals, CallMissed)
+}
+
 // -----------------------------------------------------------------------
 // Check the Passed() method.
 
@@ -138,6 +198,7 @@ func (s *RunS) TestPassed(c *C) {
 	c.Assert((&Result{Failed: 1}).Passed(), Equals, false)
 	c.Assert((&Result{Panicked: 1}).Passed(), Equals, false)
 	c.Assert((&Result{FixturePanicked: 1}).Passed(), Equals, false)
+	c.Assert((&Result{TimedOut: 1}).Passed(), Equals, false)
 	c.Assert((&Result{Missed: 1}).Passed(), Equals, false)
 	c.Assert((&Result{RunError: errors.New("!")}).Passed(), Equals, false)
 }
@@ -175,6 +236,43 @@ func (s *RunS) TestPrintFixturePanicked(c *C) {
 	c.Check(result.String(), Equals, "OOPS: 0 passed, 5 FIXTURE-PANICKED")
 }
 
+func (s *RunS) TestPrintTimedOut(c *C) {
+	result := &Result{TimedOut: 5}
+	c.Check(result.String(), Equals, "OOPS: 0 passed, 5 TIMED OUT")
+}
+
+func (s *RunS) TestPrintShuffle(c *C) {
+	result := &Result{Succeeded: 1, Shuffle: "42"}
+	c.Check(result.String(), Equals, "OK: 1 passed\nSHUFFLE=42")
+}
+
+func (s *RunS) TestPrintRepeats(c *C) {
+	result := &Result{Succeeded: 13, Failed: 2, Repeats: map[string]RepeatCounts{
+		"S.TestB": {Succeeded: 4, Failed: 1, FirstFailure: 2},
+		"S.TestA": {Succeeded: 3, Failed: 1, FirstFailure: 4},
+		"S.TestC": {Succeeded: 6},
+	}}
+	c.Check(result.String(), Equals, "OOPS: 13 passed, 2 FAILED\n"+
+		"REPEAT S.TestA: 3 passed, 1 FAILED (first at iteration 4)\n"+
+		"REPEAT S.TestB: 4 passed, 1 FAILED (first at iteration 2)")
+}
+
+func (s *RunS) TestPrintFlaky(c *C) {
+	result := &Result{Succeeded: 1, Flaky: 2}
+	c.Check(result.String(), Equals, "OK: 1 passed, 2 FLAKY")
+}
+
+func (s *RunS) TestPrintStopped(c *C) {
+	result := &Result{Failed: 1, Missed: 2, Stopped: true}
+	c.Check(result.String(), Equals, "OOPS: 0 passed, 1 FAILED, 2 MISSED\n"+
+		"STOPPED: at the first failure, remaining tests were missed")
+}
+
+func (s *RunS) TestPrintStoppedWithoutMissed(c *C) {
+	result := &Result{Failed: 1, Stopped: true}
+	c.Check(result.String(), Equals, "OOPS: 0 passed, 1 FAILED")
+}
+
 func (s *RunS) TestPrintMissed(c *C) {
 	result := &Result{Missed: 5}
 	c.Check(result.String(), Equals, "OOPS: 0 passed, 5 MISSED")
@@ -418,3 +516,909 @@ func (s *RunS) TestKeepWorkDir(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(stat.IsDir(), Equals, true)
 }
+
+// -----------------------------------------------------------------------
+// Verify that tests calling Parallel run concurrently.
+
+type ParallelHelper struct {
+	m          sync.Mutex
+	calls      []string
+	running    int
+	maxRunning int
+	sawRunning []int
+	entered    int
+	together   int
+	released   chan bool
+}
+
+// newParallelHelper returns a helper whose parallel tests wait for
+// together of them to be running before any of them finishes.
+func newParallelHelper(together int) *ParallelHelper {
+	return &ParallelHelper{together: together, released: make(chan bool)}
+}
+
+func (s *ParallelHelper) trace(name string) {
+	s.m.Lock()
+	s.calls = append(s.calls, name)
+	s.m.Unlock()
+}
+
+func (s *ParallelHelper) enter(c *C) {
+	s.m.Lock()
+	s.running++
+	if s.running > s.maxRunning {
+		s.maxRunning = s.running
+	}
+	s.entered++
+	if s.entered == s.together {
+		close(s.released)
+	}
+	s.m.Unlock()
+	select {
+	case <-s.released:
+	case <-time.After(5 * time.Second):
+		c.Fatal("parallel tests didn't run concurrently")
+	}
+	s.m.Lock()
+	s.running--
+	s.m.Unlock()
+}
+
+func (s *ParallelHelper) observe() {
+	s.m.Lock()
+	s.sawRunning = append(s.sawRunning, s.running)
+	s.m.Unlock()
+}
+
+func (s *ParallelHelper) SetUpSuite(c *C) {
+	s.trace("SetUpSuite")
+}
+
+func (s *ParallelHelper) TearDownSuite(c *C) {
+	s.observe()
+	s.trace("TearDownSuite")
+}
+
+func (s *ParallelHelper) SetUpTest(c *C) {
+	s.trace("SetUpTest")
+}
+
+func (s *ParallelHelper) TearDownTest(c *C) {
+	s.trace("TearDownTest")
+}
+
+func (s *ParallelHelper) TestA(c *C) {
+	c.Parallel()
+	s.enter(c)
+}
+
+func (s *ParallelHelper) TestB(c *C) {
+	c.Parallel()
+	s.enter(c)
+}
+
+func (s *ParallelHelper) TestC(c *C) {
+	c.Parallel()
+	s.enter(c)
+}
+
+func (s *ParallelHelper) TestSerial(c *C) {
+	s.observe()
+	s.trace("TestSerial")
+}
+
+func (s *RunS) TestParallel(c *C) {
+	helper := newParallelHelper(3)
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, Parallelism: 3})
+	c.Check(result.Succeeded, Equals, 4)
+	c.Check(result.Failed, Equals, 0)
+	c.Check(helper.maxRunning, Equals, 3)
+
+	// The serial test and TearDownSuite never overlap with parallel ones.
+	c.Check(helper.sawRunning, DeepEquals, []int{0, 0})
+
+	c.Assert(helper.calls, HasLen, 11)
+	c.Check(helper.calls[0], Equals, "SetUpSuite")
+	c.Check(helper.calls[10], Equals, "TearDownSuite")
+}
+
+func (s *RunS) TestParallelism(c *C) {
+	helper := newParallelHelper(1)
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, Parallelism: 1})
+	c.Check(result.Succeeded, Equals, 4)
+	c.Check(helper.maxRunning, Equals, 1)
+}
+
+type ParallelMisuseHelper struct{}
+
+func (s *ParallelMisuseHelper) SetUpTest(c *C) {
+	c.Parallel()
+}
+
+func (s *ParallelMisuseHelper) Test1(c *C) {}
+
+func (s *RunS) TestParallelInFixture(c *C) {
+	output := String{}
+	result := Run(&ParallelMisuseHelper{}, &RunConf{Output: &output})
+	c.Check(result.FixturePanicked, Equals, 1)
+	c.Check(result.Missed, Equals, 1)
+	c.Check(output.value, Matches, "(?s).*Panic: Parallel must be called from a test method.*")
+}
+
+// -----------------------------------------------------------------------
+// Verify that suites may run concurrently, with output kept together.
+
+type SuiteParallelHelper struct {
+	name  string
+	here  chan bool
+	other chan bool
+}
+
+func (s *SuiteParallelHelper) Test1(c *C) {
+	close(s.here)
+	select {
+	case <-s.other:
+	case <-time.After(5 * time.Second):
+		c.Fatal("suites didn't run concurrently")
+	}
+	c.Log(s.name + " 1")
+	c.Fail()
+}
+
+func (s *SuiteParallelHelper) Test2(c *C) {
+	c.Log(s.name + " 2")
+	c.Fail()
+}
+
+func (s *RunS) TestSuiteParallelism(c *C) {
+	first, second := make(chan bool), make(chan bool)
+	suites := []interface{}{
+		&SuiteParallelHelper{name: "first", here: first, other: second},
+		&SuiteParallelHelper{name: "second", here: second, other: first},
+	}
+	output := String{}
+	result := RunSuites(suites, &RunConf{Output: &output, SuiteParallelism: 2})
+	c.Check(result.Failed, Equals, 4)
+
+	expected := "^(\n-+\nFAIL: run_test\\.go:[0-9]+: SuiteParallelHelper\\.Test[12]\n\n%s [12]\n){2}"
+	c.Check(output.value, Matches, fmt.Sprintf(expected, "first")+
+		fmt.Sprintf(expected[1:], "second")+"$")
+}
+
+// -----------------------------------------------------------------------
+// Verify that tests and suites may be shuffled in a reproducible order.
+
+type ShuffleHelper struct {
+	calls []string
+}
+
+func (s *ShuffleHelper) Test0(c *C) { s.calls = append(s.calls, "Test0") }
+func (s *ShuffleHelper) Test1(c *C) { s.calls = append(s.calls, "Test1") }
+func (s *ShuffleHelper) Test2(c *C) { s.calls = append(s.calls, "Test2") }
+func (s *ShuffleHelper) Test3(c *C) { s.calls = append(s.calls, "Test3") }
+func (s *ShuffleHelper) Test4(c *C) { s.calls = append(s.calls, "Test4") }
+func (s *ShuffleHelper) Test5(c *C) { s.calls = append(s.calls, "Test5") }
+func (s *ShuffleHelper) Test6(c *C) { s.calls = append(s.calls, "Test6") }
+func (s *ShuffleHelper) Test7(c *C) { s.calls = append(s.calls, "Test7") }
+
+func (s *RunS) TestShuffle(c *C) {
+	sorted := List(&ShuffleHelper{}, &RunConf{})
+	shuffled := List(&ShuffleHelper{}, &RunConf{Shuffle: "42"})
+	c.Assert(shuffled, HasLen, len(sorted))
+	c.Assert(shuffled, Not(DeepEquals), sorted)
+	c.Assert(List(&ShuffleHelper{}, &RunConf{Shuffle: "42"}), DeepEquals, shuffled)
+
+	helper := ShuffleHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, Shuffle: "42"})
+	c.Check(result.Succeeded, Equals, 8)
+	c.Check(result.Shuffle, Equals, "42")
+	for i, name := range shuffled {
+		c.Check("ShuffleHelper."+helper.calls[i], Equals, name)
+	}
+}
+
+func (s *RunS) TestShuffleOff(c *C) {
+	sorted := List(&ShuffleHelper{}, &RunConf{})
+	c.Check(List(&ShuffleHelper{}, &RunConf{Shuffle: "off"}), DeepEquals, sorted)
+
+	output := String{}
+	result := Run(&ShuffleHelper{}, &RunConf{Output: &output, Shuffle: "off"})
+	c.Check(result.Shuffle, Equals, "")
+	c.Check(result.String(), Equals, "OK: 8 passed")
+}
+
+func (s *RunS) TestShuffleOn(c *C) {
+	output := String{}
+	result := Run(&ShuffleHelper{}, &RunConf{Output: &output, Shuffle: "on"})
+	c.Check(result.Succeeded, Equals, 8)
+	c.Check(result.Shuffle, Matches, "-?[0-9]+")
+}
+
+func (s *RunS) TestShuffleSuites(c *C) {
+	helpers := make([]*ShuffleHelper, 8)
+	suites := make([]interface{}, len(helpers))
+	for i := range helpers {
+		helpers[i] = &ShuffleHelper{}
+		suites[i] = helpers[i]
+	}
+	output := String{}
+	result := RunSuites(suites, &RunConf{Output: &output, Shuffle: "on"})
+	c.Assert(result.Succeeded, Equals, 64)
+	c.Assert(result.Shuffle, Matches, "-?[0-9]+")
+
+	// Every suite is shuffled with the reported seed.
+	replay := List(&ShuffleHelper{}, &RunConf{Shuffle: result.Shuffle})
+	for _, helper := range helpers {
+		for i, name := range replay {
+			c.Check("ShuffleHelper."+helper.calls[i], Equals, name)
+		}
+	}
+}
+
+func (s *RunS) TestShuffleBadValue(c *C) {
+	output := String{}
+	result := Run(&ShuffleHelper{}, &RunConf{Output: &output, Shuffle: "maybe"})
+	c.Check(result.String(), Equals, "ERROR: Bad shuffle value: maybe")
+	c.Check(output.value, Equals, "")
+
+	result = RunSuites([]interface{}{&ShuffleHelper{}}, &RunConf{Output: &output, Shuffle: "maybe"})
+	c.Check(result.String(), Equals, "ERROR: Bad shuffle value: maybe")
+}
+
+// -----------------------------------------------------------------------
+// Verify that tests may be run repeatedly, within a single suite setup.
+
+type RepeatHelper struct {
+	calls []string
+	n     int
+}
+
+func (s *RepeatHelper) SetUpSuite(c *C) {
+	s.calls = append(s.calls, "SetUpSuite")
+}
+
+func (s *RepeatHelper) TearDownSuite(c *C) {
+	s.calls = append(s.calls, "TearDownSuite")
+}
+
+func (s *RepeatHelper) TestFlaky(c *C) {
+	s.n++
+	c.Logf("Run %d", s.n)
+	if s.n%3 == 0 {
+		c.Fail()
+	}
+}
+
+func (s *RepeatHelper) TestParallel(c *C) {
+	c.Parallel()
+	s.calls = append(s.calls, "TestParallel")
+}
+
+func (s *RunS) TestCount(c *C) {
+	helper := RepeatHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, Count: 7})
+	c.Check(result.Succeeded, Equals, 12)
+	c.Check(result.Failed, Equals, 2)
+	c.Check(result.Repeats, DeepEquals, map[string]RepeatCounts{
+		"RepeatHelper.TestFlaky":    {Succeeded: 5, Failed: 2, FirstFailure: 3},
+		"RepeatHelper.TestParallel": {Succeeded: 7},
+	})
+	c.Check(helper.calls, HasLen, 9)
+	c.Check(helper.calls[0], Equals, "SetUpSuite")
+	c.Check(helper.calls[8], Equals, "TearDownSuite")
+
+	expected := "^\n-+\n" +
+		"FAIL: run_test\\.go:[0-9]+: RepeatHelper\\.TestFlaky \\(iteration 3\\)\n\n" +
+		"Run 3\n$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *RunS) TestCountVerbose(c *C) {
+	output := String{}
+	runConf := &RunConf{Output: &output, Verbose: true, Count: 2, Filter: "TestParallel"}
+	Run(&RepeatHelper{}, runConf)
+	expected := "^PASS: run_test\\.go:[0-9]+: RepeatHelper\\.TestParallel \\(iteration 1\\)\t *[.0-9]+s\n" +
+		"PASS: run_test\\.go:[0-9]+: RepeatHelper\\.TestParallel \\(iteration 2\\)\t *[.0-9]+s\n$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *RunS) TestUntilFail(c *C) {
+	helper := RepeatHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, UntilFail: true, Filter: "TestFlaky"})
+	c.Check(result.Succeeded, Equals, 2)
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Repeats, DeepEquals, map[string]RepeatCounts{
+		"RepeatHelper.TestFlaky": {Succeeded: 2, Failed: 1, FirstFailure: 3},
+	})
+	c.Check(output.value, Matches, "(?s).*TestFlaky \\(iteration 3\\)\n\nRun 3\n$")
+}
+
+func (s *RunS) TestUntilFailWithCount(c *C) {
+	helper := RepeatHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, UntilFail: true, Count: 2})
+	c.Check(result.Succeeded, Equals, 4)
+	c.Check(result.Failed, Equals, 0)
+	c.Check(result.Repeats, DeepEquals, map[string]RepeatCounts{
+		"RepeatHelper.TestFlaky":    {Succeeded: 2},
+		"RepeatHelper.TestParallel": {Succeeded: 2},
+	})
+	c.Check(output.value, Equals, "")
+}
+
+// -----------------------------------------------------------------------
+// Verify that failed tests may be retried, and reported as flaky.
+
+type RetryHelper struct {
+	attempts map[string]int
+}
+
+func (s *RetryHelper) attempt(c *C) int {
+	if s.attempts == nil {
+		s.attempts = make(map[string]int)
+	}
+	s.attempts[c.TestName()]++
+	n := s.attempts[c.TestName()]
+	c.Logf("Attempt %d", n)
+	return n
+}
+
+func (s *RetryHelper) TestFlaky(c *C) {
+	c.Flaky(2, "Network hiccups")
+	if s.attempt(c) < 3 {
+		c.Fail()
+	}
+}
+
+func (s *RetryHelper) TestBroken(c *C) {
+	c.Flaky(1, "Hopeless")
+	s.attempt(c)
+	c.Fail()
+}
+
+func (s *RetryHelper) TestPanic(c *C) {
+	if s.attempt(c) < 2 {
+		panic("Boom!")
+	}
+}
+
+func (s *RetryHelper) TestParallel(c *C) {
+	c.Parallel()
+	if s.attempt(c) < 2 {
+		c.Fail()
+	}
+}
+
+func (s *RunS) TestFlaky(c *C) {
+	helper := RetryHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, Filter: "TestFlaky|TestBroken"})
+	c.Check(result.Succeeded, Equals, 0)
+	c.Check(result.Flaky, Equals, 1)
+	c.Check(result.Failed, Equals, 1)
+	c.Check(helper.attempts, DeepEquals, map[string]int{
+		"RetryHelper.TestFlaky":  3,
+		"RetryHelper.TestBroken": 2,
+	})
+
+	expected := "^\n-+\n" +
+		"FAIL: run_test\\.go:[0-9]+: RetryHelper\\.TestBroken\n\n" +
+		"Attempt 1\n" +
+		"\\.\\.\\. Retrying: attempt 2 of 2 \\(Hopeless\\)\n" +
+		"Attempt 2\n" +
+		"\n-+\n" +
+		"FLAKY: run_test\\.go:[0-9]+: RetryHelper\\.TestFlaky\n\n" +
+		"Attempt 1\n" +
+		"\\.\\.\\. Retrying: attempt 2 of 3 \\(Network hiccups\\)\n" +
+		"Attempt 2\n" +
+		"\\.\\.\\. Retrying: attempt 3 of 3 \\(Network hiccups\\)\n" +
+		"Attempt 3\n$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *RunS) TestRetries(c *C) {
+	helper := RetryHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, Retries: 1, Filter: "TestPanic|TestParallel"})
+	c.Check(result.Succeeded, Equals, 0)
+	c.Check(result.Panicked, Equals, 0)
+	c.Check(result.Failed, Equals, 0)
+	c.Check(result.Flaky, Equals, 2)
+	c.Check(output.value, Matches, "(?s).*FLAKY: .*RetryHelper\\.TestPanic\n\n"+
+		"Attempt 1\n.*Boom!.*Retrying: attempt 2 of 2\nAttempt 2\n.*")
+	c.Check(output.value, Matches, "(?s).*FLAKY: .*RetryHelper\\.TestParallel\n.*")
+}
+
+func (s *RunS) TestNoRetries(c *C) {
+	helper := RetryHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, Filter: "TestPanic|TestParallel"})
+	c.Check(result.Panicked, Equals, 1)
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Flaky, Equals, 0)
+	c.Check(helper.attempts, DeepEquals, map[string]int{
+		"RetryHelper.TestPanic":    1,
+		"RetryHelper.TestParallel": 1,
+	})
+}
+
+// -----------------------------------------------------------------------
+// Verify that tests may be split into shards.
+
+func (s *RunS) TestShard(c *C) {
+	all := List(&ShuffleHelper{}, &RunConf{})
+	var sharded []string
+	for i := 0; i < 3; i++ {
+		shard := List(&ShuffleHelper{}, &RunConf{Shard: fmt.Sprintf("%d/3", i)})
+		c.Check(List(&ShuffleHelper{}, &RunConf{Shard: fmt.Sprintf("%d/3", i)}), DeepEquals, shard)
+		sharded = append(sharded, shard...)
+	}
+	sort.Strings(sharded)
+	c.Check(sharded, DeepEquals, all)
+	c.Check(List(&ShuffleHelper{}, &RunConf{Shard: "0/1"}), DeepEquals, all)
+}
+
+func (s *RunS) TestShardWithoutTests(c *C) {
+	// Find a shard with none of the tests of the suite.
+	shard := ""
+	for i := 0; i < 8 && shard == ""; i++ {
+		if List(&FixtureHelper{}, &RunConf{Shard: fmt.Sprintf("%d/8", i)}) == nil {
+			shard = fmt.Sprintf("%d/8", i)
+		}
+	}
+	c.Assert(shard, Not(Equals), "")
+	helper := FixtureHelper{}
+	output := String{}
+	result := Run(&helper, &RunConf{Output: &output, Shard: shard})
+	c.Check(result.String(), Equals, "OK: 0 passed")
+	c.Check(helper.calls, IsNil)
+}
+
+func (s *RunS) TestShardBadValue(c *C) {
+	for _, shard := range []string{"1", "a/2", "1/b", "2/2", "-1/2", "0/0"} {
+		output := String{}
+		result := Run(&ShuffleHelper{}, &RunConf{Output: &output, Shard: shard})
+		c.Check(result.String(), Equals, "ERROR: Bad shard value: "+shard)
+	}
+}
+
+// -----------------------------------------------------------------------
+// Verify that the run may stop at the first failure, running teardowns.
+
+type FailFastHelper struct {
+	calls []string
+	fail  bool
+}
+
+func (s *FailFastHelper) SetUpSuite(c *C) {
+	s.calls = append(s.calls, "SetUpSuite")
+}
+
+func (s *FailFastHelper) TearDownSuite(c *C) {
+	s.calls = append(s.calls, "TearDownSuite")
+}
+
+func (s *FailFastHelper) TearDownTest(c *C) {
+	s.calls = append(s.calls, "TearDownTest")
+}
+
+func (s *FailFastHelper) Test1(c *C) {
+	s.calls = append(s.calls, "Test1")
+	if s.fail {
+		c.Fail()
+	}
+}
+
+func (s *FailFastHelper) Test2(c *C) {
+	s.calls = append(s.calls, "Test2")
+}
+
+func (s *FailFastHelper) Test3(c *C) {
+	c.Parallel()
+	s.calls = append(s.calls, "Test3")
+}
+
+func (s *RunS) TestFailFast(c *C) {
+	first := &FailFastHelper{fail: true}
+	second := &FailFastHelper{}
+	output := String{}
+	result := RunSuites([]interface{}{first, second}, &RunConf{Output: &output, FailFast: true})
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Missed, Equals, 5)
+	c.Check(result.Stopped, Equals, true)
+	c.Check(first.calls, DeepEquals, []string{"SetUpSuite", "Test1", "TearDownTest", "TearDownSuite"})
+	c.Check(second.calls, IsNil)
+}
+
+func (s *RunS) TestFailFastOnLastTest(c *C) {
+	helper := &FailFastHelper{fail: true}
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, FailFast: true, Filter: "Test1"})
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Missed, Equals, 0)
+	c.Check(result.String(), Equals, "OOPS: 0 passed, 1 FAILED")
+}
+
+func (s *RunS) TestFailFastParallelSuites(c *C) {
+	first := &FailFastHelper{fail: true}
+	second := &FailFastHelper{}
+	output := String{}
+	runConf := &RunConf{Output: &output, FailFast: true, SuiteParallelism: 2}
+	result := RunSuites([]interface{}{first, second}, runConf)
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Stopped, Equals, true)
+	c.Check(result.Succeeded+result.Missed, Equals, 5)
+}
+
+func (s *RunS) TestFailFastOff(c *C) {
+	first := &FailFastHelper{fail: true}
+	second := &FailFastHelper{}
+	output := String{}
+	result := RunSuites([]interface{}{first, second}, &RunConf{Output: &output})
+	c.Check(result.Failed, Equals, 1)
+	c.Check(result.Succeeded, Equals, 5)
+	c.Check(result.Missed, Equals, 0)
+	c.Check(result.Stopped, Equals, false)
+}
+
+// -----------------------------------------------------------------------
+// Verify that suites may be run as subtests of a testing.T.
+
+type TestingTHelper struct {
+	m     sync.Mutex
+	calls []string
+}
+
+func (s *TestingTHelper) trace(name string) {
+	s.m.Lock()
+	s.calls = append(s.calls, name)
+	s.m.Unlock()
+}
+
+func (s *TestingTHelper) SetUpSuite(c *C) {
+	s.trace("SetUpSuite")
+}
+
+func (s *TestingTHelper) TearDownSuite(c *C) {
+	s.trace("TearDownSuite")
+}
+
+func (s *TestingTHelper) Test1(c *C) {
+	c.Parallel()
+	s.trace("Test1")
+}
+
+func (s *TestingTHelper) Test2(c *C) {
+	s.trace("Test2")
+}
+
+func (s *TestingTHelper) Test3(c *C) {
+	s.trace("Test3")
+	c.Skip("Not today")
+}
+
+func TestRunSuitesT(t *testing.T) {
+	helper := &TestingTHelper{}
+	output := String{}
+	result := RunSuitesT(t, []interface{}{helper}, &RunConf{Output: &output})
+	if result.Succeeded != 2 || result.Skipped != 1 || !result.Passed() {
+		t.Errorf("Unexpected result: %s", result)
+	}
+	expected := "SetUpSuite Test2 Test3 Test1 TearDownSuite"
+	if calls := strings.Join(helper.calls, " "); calls != expected {
+		t.Errorf("Got calls %q, expected %q", calls, expected)
+	}
+	if output.value != "" {
+		t.Errorf("Unexpected output: %q", output.value)
+	}
+}
+
+func TestRunSuitesTWithoutTests(t *testing.T) {
+	helper := &TestingTHelper{}
+	output := String{}
+	result := RunSuitesT(t, []interface{}{helper}, &RunConf{Output: &output, Filter: "TestNone"})
+	if result.String() != "OK: 0 passed" || helper.calls != nil {
+		t.Errorf("Unexpected result %s, with calls %q", result, helper.calls)
+	}
+}
+
+type ReportTHelper struct{}
+
+func (s *ReportTHelper) SetUpTest(c *C) {
+	if c.TestName() == "ReportTHelper.Test4SetUpPanic" {
+		panic("SetUpTest boom")
+	}
+}
+
+func (s *ReportTHelper) Test1Fail(c *C) {
+	c.Error("Oops")
+}
+
+func (s *ReportTHelper) Test2Panic(c *C) {
+	panic("Boom")
+}
+
+func (s *ReportTHelper) Test3Skip(c *C) {
+	c.Skip("Not now")
+}
+
+func (s *ReportTHelper) Test4SetUpPanic(c *C) {}
+
+func (s *ReportTHelper) Test5Missed(c *C) {}
+
+// The suite fails, so it's run in a separate process to check how each
+// outcome gets reported to the testing package.
+func TestRunSuitesTReports(t *testing.T) {
+	if os.Getenv("CHECK_REPORT_T") != "" {
+		RunSuitesT(t, []interface{}{&ReportTHelper{}}, &RunConf{Output: &String{}})
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunSuitesTReports$", "-test.v")
+	cmd.Env = append(os.Environ(), "CHECK_REPORT_T=1")
+	output, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("Expected the run to fail, got %v with output:\n%s", err, output)
+	}
+	for _, expected := range []string{
+		"(?m)^ +run_test\\.go:[0-9]+: \n +run_test\\.go:[0-9]+:\n +c\\.Error\\(\"Oops\"\\)\n +\\.\\.\\. Error: Oops$",
+		"(?m)^ +run_test\\.go:[0-9]+: \n +\\.\\.\\. Panic: Boom ",
+		"(?m)^ +run_test\\.go:[0-9]+: Not now$",
+		"(?m)^ +run_test\\.go:[0-9]+: \n +\\.\\.\\. Panic: Fixture has panicked \\(see related PANIC\\)$",
+		"(?m)^ +run_test\\.go:[0-9]+: Missed after an earlier problem$",
+		"(?m)^ +--- FAIL: TestRunSuitesTReports/ReportTHelper/Test1Fail ",
+		"(?m)^ +--- FAIL: TestRunSuitesTReports/ReportTHelper/Test2Panic ",
+		"(?m)^ +--- SKIP: TestRunSuitesTReports/ReportTHelper/Test3Skip ",
+		"(?m)^ +--- FAIL: TestRunSuitesTReports/ReportTHelper/Test4SetUpPanic ",
+		"(?m)^ +--- SKIP: TestRunSuitesTReports/ReportTHelper/Test5Missed ",
+	} {
+		if !regexp.MustCompile(expected).Match(output) {
+			t.Errorf("Output doesn't match %q:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(string(output), "run.go:") {
+		t.Errorf("Output refers to run.go:\n%s", output)
+	}
+}
+
+// -----------------------------------------------------------------------
+// Verify that tests running past their timeout are reported and left behind.
+
+type TimeoutHelper struct {
+	m       sync.Mutex
+	calls   []string
+	hangOn  string
+	release chan bool
+}
+
+func (s *TimeoutHelper) trace(name string) {
+	s.m.Lock()
+	s.calls = append(s.calls, name)
+	s.m.Unlock()
+	if s.hangOn == name {
+		<-s.release
+	}
+}
+
+func (s *TimeoutHelper) traced() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *TimeoutHelper) SetUpSuite(c *C) {
+	s.trace("SetUpSuite")
+}
+
+func (s *TimeoutHelper) TearDownSuite(c *C) {
+	s.trace("TearDownSuite")
+}
+
+func (s *TimeoutHelper) SetUpTest(c *C) {
+	s.trace("SetUpTest")
+}
+
+func (s *TimeoutHelper) TearDownTest(c *C) {
+	s.trace("TearDownTest")
+}
+
+func (s *TimeoutHelper) Test1(c *C) {
+	s.trace("Test1")
+}
+
+func (s *TimeoutHelper) Test2(c *C) {
+	s.trace("Test2")
+}
+
+func (s *RunS) TestTimeout(c *C) {
+	helper := &TimeoutHelper{hangOn: "Test1", release: make(chan bool)}
+	defer close(helper.release)
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, TestTimeout: 50 * time.Millisecond})
+	c.Check(result.TimedOut, Equals, 1)
+	c.Check(result.Succeeded, Equals, 1)
+	c.Check(result.Passed(), Equals, false)
+	c.Check(helper.traced(), DeepEquals, []string{
+		"SetUpSuite", "SetUpTest", "Test1",
+		"SetUpTest", "Test2", "TearDownTest", "TearDownSuite"})
+
+	expected := "^\n-+\n" +
+		"TIMEOUT: run_test\\.go:[0-9]+: TimeoutHelper\\.Test1\n\n" +
+		"\\.\\.\\. Timeout: test still running after 50ms\n\n" +
+		"goroutine [0-9]+ \\[running\\]:\n(.|\n)*" +
+		"TimeoutHelper\\)\\.trace(.|\n)*$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *RunS) TestTimeoutInSetUpTest(c *C) {
+	helper := &TimeoutHelper{hangOn: "SetUpTest", release: make(chan bool)}
+	defer close(helper.release)
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, TestTimeout: 50 * time.Millisecond})
+	c.Check(result.TimedOut, Equals, 2)
+	c.Check(result.FixturePanicked, Equals, 0)
+	c.Check(helper.traced(), DeepEquals, []string{
+		"SetUpSuite", "SetUpTest", "SetUpTest", "TearDownSuite"})
+}
+
+func (s *RunS) TestAbortOnTimeout(c *C) {
+	helper := &TimeoutHelper{hangOn: "Test1", release: make(chan bool)}
+	defer close(helper.release)
+	output := String{}
+	runConf := RunConf{Output: &output, TestTimeout: 50 * time.Millisecond, AbortOnTimeout: true}
+	result := Run(helper, &runConf)
+	c.Check(result.TimedOut, Equals, 1)
+	c.Check(result.Missed, Equals, 1)
+	c.Check(result.Succeeded, Equals, 0)
+	c.Check(helper.traced(), DeepEquals, []string{
+		"SetUpSuite", "SetUpTest", "Test1", "TearDownSuite"})
+}
+
+type SetTimeoutHelper struct {
+	release chan bool
+}
+
+func (s *SetTimeoutHelper) TestHang(c *C) {
+	c.SetTimeout(50 * time.Millisecond)
+	<-s.release
+}
+
+func (s *SetTimeoutHelper) TestNoTimeout(c *C) {
+	c.SetTimeout(0)
+	time.Sleep(100 * time.Millisecond)
+}
+
+func (s *RunS) TestSetTimeout(c *C) {
+	helper := &SetTimeoutHelper{release: make(chan bool)}
+	defer close(helper.release)
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, TestTimeout: 75 * time.Millisecond})
+	c.Check(result.TimedOut, Equals, 1)
+	c.Check(result.Succeeded, Equals, 1)
+	c.Check(output.value, Matches, "(?s).*TIMEOUT: .*SetTimeoutHelper\\.TestHang\n\n"+
+		"\\.\\.\\. Timeout: test still running after 50ms\n.*")
+}
+
+type LeftBehindHelper struct {
+	release   chan bool
+	done      chan bool
+	subtested bool
+}
+
+func (s *LeftBehindHelper) TestHang(c *C) {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.release:
+			c.Run("late", func(c *C) { s.subtested = true })
+			return
+		default:
+			c.ExpectFailure("Still running")
+			runtime.Gosched()
+		}
+	}
+}
+
+func (s *RunS) TestTimeoutLeavesTestBehind(c *C) {
+	helper := &LeftBehindHelper{release: make(chan bool), done: make(chan bool)}
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, TestTimeout: 50 * time.Millisecond})
+	close(helper.release)
+	<-helper.done
+	c.Check(result.TimedOut, Equals, 1)
+	c.Check(result.ExpectedFailures, Equals, 0)
+	c.Check(result.Tests[0].ExpectedFailure, Equals, false)
+	c.Check(result.Tests[0].Reason, Equals, "")
+	c.Check(helper.subtested, Equals, false)
+}
+
+// -----------------------------------------------------------------------
+// Verify that subtests are run, filtered and reported on their own.
+
+type SubtestHelper struct {
+	calls   []string
+	results []bool
+}
+
+func (s *SubtestHelper) TestTable(c *C) {
+	rows := []struct {
+		name string
+		fail bool
+	}{{"one", false}, {"two", true}, {"three", false}}
+	for _, row := range rows {
+		ok := c.Run(row.name, func(c *C) {
+			s.calls = append(s.calls, c.TestName())
+			if row.fail {
+				c.Fatal("row failed")
+			}
+		})
+		s.results = append(s.results, ok)
+	}
+}
+
+func (s *SubtestHelper) TestNested(c *C) {
+	c.Run("outer", func(c *C) {
+		c.Run("inner name", func(c *C) {
+			s.calls = append(s.calls, c.TestName())
+		})
+	})
+}
+
+func (s *RunS) TestSubtests(c *C) {
+	helper := &SubtestHelper{}
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output})
+	c.Check(result.Succeeded, Equals, 5)
+	c.Check(result.Failed, Equals, 2)
+	c.Check(helper.calls, DeepEquals, []string{
+		"SubtestHelper.TestNested/outer/inner_name",
+		"SubtestHelper.TestTable/one",
+		"SubtestHelper.TestTable/two",
+		"SubtestHelper.TestTable/three",
+	})
+	c.Check(helper.results, DeepEquals, []bool{true, false, true})
+
+	expected := "^\n-+\n" +
+		"FAIL: run_test\\.go:[0-9]+: SubtestHelper\\.TestTable/two\n\n" +
+		"run_test\\.go:[0-9]+:\n" +
+		"    c\\.Fatal\\(\"row failed\"\\)\n" +
+		"\\.\\.\\. Error: row failed\n\n" +
+		"\n-+\n" +
+		"FAIL: run_test\\.go:[0-9]+: SubtestHelper\\.TestTable\n\n" +
+		"\\.\\.\\. Subtest failed: SubtestHelper\\.TestTable/two\n$"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *RunS) TestSubtestsVerbose(c *C) {
+	helper := &SubtestHelper{}
+	output := String{}
+	Run(helper, &RunConf{Output: &output, Verbose: true, Filter: "Nested"})
+
+	expected := "PASS: run_test\\.go:[0-9]+: SubtestHelper\\.TestNested/outer/inner_name\t *[.0-9]+s\n" +
+		"PASS: run_test\\.go:[0-9]+: SubtestHelper\\.TestNested/outer\t *[.0-9]+s\n" +
+		"PASS: run_test\\.go:[0-9]+: SubtestHelper\\.TestNested\t *[.0-9]+s\n"
+	c.Check(output.value, Matches, expected)
+}
+
+func (s *RunS) TestFilterSubtests(c *C) {
+	helper := &SubtestHelper{}
+	output := String{}
+	result := Run(helper, &RunConf{Output: &output, Filter: "Table/^t"})
+	c.Check(result.Succeeded, Equals, 1)
+	c.Check(result.Failed, Equals, 2)
+	c.Check(helper.calls, DeepEquals, []string{
+		"SubtestHelper.TestTable/two",
+		"SubtestHelper.TestTable/three",
+	})
+
+	helper = &SubtestHelper{}
+	result = Run(helper, &RunConf{Output: &output, Filter: "/outer/[(/]|inner"})
+	c.Check(result.Succeeded, Equals, 4)
+	c.Check(helper.calls, DeepEquals, []string{
+		"SubtestHelper.TestNested/outer/inner_name",
+	})
+}
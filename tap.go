@@ -0,0 +1,136 @@
+package check
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------
+// Results in the Test Anything Protocol, version 13.
+
+type tapReporter struct {
+	writer  io.Writer
+	planned int // Number of tests, or -1 if the plan is written at the end.
+	n       int
+}
+
+// Create a reporter for the given number of tests, or for an unknown
+// number of tests if planned is negative.
+func newTAPReporter(writer io.Writer, planned int) *tapReporter {
+	return &tapReporter{writer: writer, planned: planned}
+}
+
+// Count the tests selected to run, if each of them is reported exactly
+// once, or return -1 otherwise.
+func tapPlan(suites []interface{}, runConf *RunConf) int {
+	if runConf.Count > 1 || runConf.UntilFail {
+		// Repetitions may stop early.
+		return -1
+	}
+	planned := 0
+	for _, suite := range suites {
+		planned += len(List(suite, runConf))
+	}
+	return planned
+}
+
+func (r *tapReporter) RunStarted() {
+	fmt.Fprintln(r.writer, "TAP version 13")
+	if r.planned >= 0 {
+		fmt.Fprintf(r.writer, "1..%d\n", r.planned)
+	}
+}
+
+func (r *tapReporter) SuiteStarted(suite string)                  {}
+func (r *tapReporter) CallStarted(call *CallInfo)                 {}
+func (r *tapReporter) SuiteFinished(suite string, result *Result) {}
+
+// Report a test as an ok or not ok line, with a YAML block describing
+// its problem, if any. Problems of fixtures and subtests, which aren't
+// numbered, are reported as comments instead.
+func (r *tapReporter) CallFinished(result *CallResult) {
+	var ok bool
+	var directive, message string
+	switch result.Status {
+	case CallSucceeded:
+		ok = true
+		if result.ExpectedFailure {
+			ok, directive = false, "TODO "+result.Reason
+		} else if result.Retries > 0 {
+			message = fmt.Sprintf("Succeeded after %d failed attempts", result.Retries)
+		}
+	case CallSkipped:
+		ok, directive = true, "SKIP "+result.Reason
+	case CallFailed:
+		message = "Failed"
+		if result.ExpectedFailure {
+			message = "Succeeded, but was expected to fail: " + result.Reason
+		}
+	case CallPanicked:
+		message = "Panicked"
+	case CallFixturePanicked:
+		message = "Fixture has panicked"
+	case CallMissed:
+		message = "Missed"
+	case CallTimedOut:
+		message = "Timed out"
+	default:
+		return
+	}
+
+	name := result.Name
+	if result.Iteration > 0 {
+		name += fmt.Sprintf(" (iteration %d)", result.Iteration)
+	}
+	if result.Fixture || strings.Contains(result.Name, "/") {
+		if !ok {
+			fmt.Fprintf(r.writer, "# %s: %s\n", result.Status, name)
+			if log := strings.TrimRight(result.Log, "\n"); log != "" {
+				for _, line := range strings.Split(log, "\n") {
+					fmt.Fprintf(r.writer, "# %s\n", line)
+				}
+			}
+		}
+		return
+	}
+
+	r.n++
+	line := fmt.Sprintf("ok %d - %s", r.n, name)
+	if !ok {
+		line = "not " + line
+	}
+	if directive != "" {
+		line += " # " + strings.TrimSpace(directive)
+	}
+	fmt.Fprintln(r.writer, line)
+	if message != "" {
+		r.writeDiagnostic(result, message)
+	}
+}
+
+func (r *tapReporter) writeDiagnostic(result *CallResult, message string) {
+	fmt.Fprintf(r.writer, "  ---\n  message: %s\n", strconv.Quote(message))
+	if result.FailureFile != "" {
+		fmt.Fprintf(r.writer, "  at:\n    file: %s\n    line: %d\n",
+			strconv.Quote(nicePath(result.FailureFile)), result.FailureLine)
+	}
+	fmt.Fprintf(r.writer, "  duration_ms: %.3f\n", result.Duration.Seconds()*1000)
+	if log := strings.TrimRight(result.Log, "\n"); log != "" {
+		fmt.Fprintln(r.writer, "  log: |")
+		for _, line := range strings.Split(log, "\n") {
+			fmt.Fprintf(r.writer, "    %s\n", line)
+		}
+	}
+	fmt.Fprintln(r.writer, "  ...")
+}
+
+func (r *tapReporter) RunFinished(result *Result) {
+	if result.RunError != nil {
+		fmt.Fprintf(r.writer, "Bail out! %s\n", result.RunError)
+	}
+	if r.planned < 0 {
+		fmt.Fprintf(r.writer, "1..%d\n", r.n)
+	}
+}
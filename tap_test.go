@@ -0,0 +1,74 @@
+package check_test
+
+import (
+	"bytes"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&tapS{})
+
+type tapS struct{}
+
+func (s *tapS) TestTAP(c *C) {
+	helper := OutcomeHelper{}
+	var buf bytes.Buffer
+	runCapturing(&RunConf{TAPWriter: &buf}, &helper)
+	c.Check(buf.String(), Matches, ""+
+		"TAP version 13\n"+
+		"1\\.\\.5\n"+
+		"not ok 1 - OutcomeHelper\\.TestExpectFailure # TODO Bug #1\n"+
+		"not ok 2 - OutcomeHelper\\.TestFail\n"+
+		"  ---\n"+
+		"  message: \"Failed\"\n"+
+		"  at:\n"+
+		"    file: \"check_test\\.go\"\n"+
+		"    line: "+strconv.Itoa(helper.failLine)+"\n"+
+		"  duration_ms: [0-9.]+\n"+
+		"  log: \\|\n"+
+		"    Failing\n"+
+		"    check_test\\.go:[0-9]+:\n"+
+		"        c\\.Check\\(1, check\\.Equals, 2\\)\n"+
+		"    \\.\\.\\. obtained int = 1\n"+
+		"    \\.\\.\\. expected int = 2\n"+
+		"  \\.\\.\\.\n"+
+		"not ok 3 - OutcomeHelper\\.TestPanic\n"+
+		"  ---\n"+
+		"  message: \"Panicked\"\n"+
+		"  at:\n"+
+		"    file: \"check_test\\.go\"\n"+
+		"    line: "+strconv.Itoa(helper.panicLine)+"\n"+
+		"  duration_ms: [0-9.]+\n"+
+		"  log: \\|\n"+
+		"    \\.\\.\\. Panic: Boom! (?s:.*?)\n"+
+		"  \\.\\.\\.\n"+
+		"ok 4 - OutcomeHelper\\.TestPass\n"+
+		"ok 5 - OutcomeHelper\\.TestSkip # SKIP Not now\n")
+}
+
+func (s *tapS) TestTAPFixturePanic(c *C) {
+	var buf bytes.Buffer
+	runCapturing(&RunConf{TAPWriter: &buf}, &FixtureHelper{panicOn: "SetUpSuite"})
+	c.Check(buf.String(), Matches, "(?s)"+
+		"TAP version 13\n"+
+		"1\\.\\.2\n"+
+		"# PANIC: FixtureHelper\\.SetUpSuite\n"+
+		"# \\.\\.\\. Panic: SetUpSuite .*"+
+		"not ok 1 - FixtureHelper\\.Test1\n"+
+		"  ---\n"+
+		"  message: \"Missed\"\n"+
+		".*"+
+		"not ok 2 - FixtureHelper\\.Test2\n"+
+		".*")
+}
+
+func (s *tapS) TestTAPWithCount(c *C) {
+	var buf bytes.Buffer
+	runCapturing(&RunConf{TAPWriter: &buf, Count: 2}, &SuccessHelper{})
+	c.Check(buf.String(), Equals, ""+
+		"TAP version 13\n"+
+		"ok 1 - SuccessHelper.TestLogAndSucceed (iteration 1)\n"+
+		"ok 2 - SuccessHelper.TestLogAndSucceed (iteration 2)\n"+
+		"1..2\n")
+}
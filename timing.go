@@ -0,0 +1,102 @@
+package check
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+// Report of the slowest tests, and of the time spent in suite fixtures.
+
+type timingReporter struct {
+	writer  io.Writer
+	slowest int
+	tests   []testTiming
+	suites  []*suiteTiming
+}
+
+type testTiming struct {
+	name     string
+	duration time.Duration
+}
+
+type suiteTiming struct {
+	name          string
+	setUpSuite    time.Duration
+	tearDownSuite time.Duration
+}
+
+func newTimingReporter(writer io.Writer, slowest int) *timingReporter {
+	return &timingReporter{writer: writer, slowest: slowest}
+}
+
+func (r *timingReporter) RunStarted()                                {}
+func (r *timingReporter) SuiteStarted(suite string)                  {}
+func (r *timingReporter) CallStarted(call *CallInfo)                 {}
+func (r *timingReporter) SuiteFinished(suite string, result *Result) {}
+
+func (r *timingReporter) CallFinished(result *CallResult) {
+	if result.Status == CallMissed || strings.Contains(result.Name, "/") {
+		return
+	}
+	if !result.Fixture {
+		name := result.Name
+		if result.Iteration > 0 {
+			name += fmt.Sprintf(" (iteration %d)", result.Iteration)
+		}
+		r.tests = append(r.tests, testTiming{name, result.Wall})
+		return
+	}
+	switch result.Method {
+	case "SetUpSuite":
+		r.suite(result.Suite).setUpSuite += result.Wall
+	case "TearDownSuite":
+		r.suite(result.Suite).tearDownSuite += result.Wall
+	}
+}
+
+func (r *timingReporter) suite(name string) *suiteTiming {
+	for _, suite := range r.suites {
+		if suite.name == name {
+			return suite
+		}
+	}
+	suite := &suiteTiming{name: name}
+	r.suites = append(r.suites, suite)
+	return suite
+}
+
+func (r *timingReporter) RunFinished(result *Result) {
+	sort.SliceStable(r.tests, func(i, j int) bool {
+		return r.tests[i].duration > r.tests[j].duration
+	})
+	tests := r.tests
+	if len(tests) > r.slowest {
+		tests = tests[:r.slowest]
+	}
+	if len(tests) > 0 {
+		fmt.Fprintf(r.writer, "SLOWEST %d TESTS:\n", len(tests))
+		for _, test := range tests {
+			fmt.Fprintf(r.writer, "%9.3fs %s\n", test.duration.Seconds(), test.name)
+		}
+	}
+
+	sort.SliceStable(r.suites, func(i, j int) bool {
+		return r.suites[i].total() > r.suites[j].total()
+	})
+	if len(r.suites) > 0 {
+		fmt.Fprintln(r.writer, "SUITE FIXTURES:")
+		for _, suite := range r.suites {
+			fmt.Fprintf(r.writer, "%9.3fs %s (SetUpSuite %.3fs, TearDownSuite %.3fs)\n",
+				suite.total().Seconds(), suite.name,
+				suite.setUpSuite.Seconds(), suite.tearDownSuite.Seconds())
+		}
+	}
+}
+
+func (suite *suiteTiming) total() time.Duration {
+	return suite.setUpSuite + suite.tearDownSuite
+}
@@ -0,0 +1,89 @@
+package check_test
+
+import (
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&timingS{})
+
+type timingS struct{}
+
+// A clock which only moves when told to, so that calls take exactly
+// as long as the tests say.
+type fakeClock struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.m.Lock()
+	defer clock.m.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.m.Lock()
+	clock.now = clock.now.Add(d)
+	clock.m.Unlock()
+}
+
+type TimingHelper struct {
+	clock fakeClock
+}
+
+func (s *TimingHelper) SetUpSuite(c *C) {
+	s.clock.Advance(10 * time.Millisecond)
+}
+
+func (s *TimingHelper) SetUpTest(c *C) {
+	s.clock.Advance(5 * time.Millisecond)
+}
+
+func (s *TimingHelper) TestFast(c *C) {}
+
+func (s *TimingHelper) TestSlow(c *C) {
+	c.StopTimer()
+	s.clock.Advance(50 * time.Millisecond)
+}
+
+func runTiming(runConf *RunConf) (*Result, string) {
+	helper := &TimingHelper{}
+	SetClock(runConf, helper.clock.Now)
+	return runCapturing(runConf, helper)
+}
+
+func (s *timingS) TestSlowest(c *C) {
+	result, output := runTiming(&RunConf{Slowest: 2})
+	c.Check(result.Passed(), Equals, true)
+	c.Check(output, Equals, ""+
+		"SLOWEST 2 TESTS:\n"+
+		"    0.055s TimingHelper.TestSlow\n"+
+		"    0.005s TimingHelper.TestFast\n"+
+		"SUITE FIXTURES:\n"+
+		"    0.010s TimingHelper (SetUpSuite 0.010s, TearDownSuite 0.000s)\n")
+}
+
+func (s *timingS) TestSlowTest(c *C) {
+	result, output := runTiming(&RunConf{SlowTest: 25 * time.Millisecond})
+	c.Check(result.Passed(), Equals, true)
+	c.Check(result.Succeeded, Equals, 2)
+	c.Check(output, Matches, "\n-+\n"+
+		"SLOW: timing_test\\.go:[0-9]+: TimingHelper\\.TestSlow\n\n"+
+		"\\.\\.\\. Slow test: took 0\\.055s, longer than the 25ms limit\n\n")
+	c.Assert(result.Tests, HasLen, 2)
+	c.Check(result.Tests[0].Slow, Equals, false)
+	c.Check(result.Tests[1].Slow, Equals, true)
+	c.Check(result.Tests[1].Wall, Equals, 55*time.Millisecond)
+}
+
+func (s *timingS) TestSlowTestFails(c *C) {
+	result, output := runTiming(&RunConf{SlowTest: 25 * time.Millisecond, SlowTestFails: true})
+	c.Check(result.Succeeded, Equals, 1)
+	c.Check(result.Failed, Equals, 1)
+	c.Check(output, Matches, "\n-+\n"+
+		"FAIL: timing_test\\.go:[0-9]+: TimingHelper\\.TestSlow\n\n"+
+		"\\.\\.\\. Slow test: took 0\\.055s, longer than the 25ms limit\n\n")
+}